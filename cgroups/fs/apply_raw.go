@@ -69,16 +69,19 @@ func Apply(c *cgroups.Cgroup, pid int) (map[string]string, error) {
 			cgroups.RemovePaths(paths)
 		}
 	}()
+	// err is not shadowed in the loop so that the deferred cleanup sees the failure
 	for name, sys := range subsystems {
-		if err := sys.Set(d); err != nil {
+		if err = sys.Set(d); err != nil {
 			return nil, err
 		}
 		// FIXME: Apply should, ideally, be reentrant or be broken up into a separate
 		// create and join phase so that the cgroup hierarchy for a container can be
 		// created then join consists of writing the process pids to cgroup.procs
-		p, err := d.path(name)
+		var p string
+		p, err = d.path(name)
 		if err != nil {
 			if cgroups.IsNotFound(err) {
+				err = nil
 				continue
 			}
 			return nil, err
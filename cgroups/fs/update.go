@@ -0,0 +1,210 @@
+package fs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/devices"
+)
+
+// Update writes the values of c that differ from old to the cgroups of a running
+// container at paths.  A value changed to zero restores the kernel's default.
+// Also available in the systemd implementation.
+func Update(old, c *cgroups.Cgroup, paths map[string]string) error {
+	if err := updateMemory(old, c, paths["memory"]); err != nil {
+		return err
+	}
+	if err := updateCpu(old, c, paths["cpu"]); err != nil {
+		return err
+	}
+	if err := updateCpuset(old, c, paths["cpuset"]); err != nil {
+		return err
+	}
+	return updateDevices(old, c, paths["devices"])
+}
+
+func updateMemory(old, c *cgroups.Cgroup, dir string) error {
+	var (
+		limitChanged = c.Memory != old.Memory
+		swapChanged  = memorySwapLimit(c) != memorySwapLimit(old)
+	)
+	if !limitChanged && !swapChanged && c.MemoryReservation == old.MemoryReservation {
+		return nil
+	}
+	if dir == "" {
+		return cgroups.NewNotFoundError("memory")
+	}
+
+	writeLimit := func() error {
+		if !limitChanged {
+			return nil
+		}
+		return writeFile(dir, "memory.limit_in_bytes", limitValue(c.Memory))
+	}
+	writeSwap := func() error {
+		if !swapChanged {
+			return nil
+		}
+		return writeFile(dir, "memory.memsw.limit_in_bytes", limitValue(memorySwapLimit(c)))
+	}
+
+	// the memory limit cannot be raised above the swap limit and the swap limit
+	// cannot be lowered below the memory limit
+	order := []func() error{writeLimit, writeSwap}
+	if c.Memory == 0 || (old.Memory != 0 && c.Memory > old.Memory) {
+		order = []func() error{writeSwap, writeLimit}
+	}
+	for _, write := range order {
+		if err := write(); err != nil {
+			return err
+		}
+	}
+
+	if c.MemoryReservation != old.MemoryReservation {
+		if err := writeFile(dir, "memory.soft_limit_in_bytes", limitValue(c.MemoryReservation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memorySwapLimit returns the value written to memory.memsw.limit_in_bytes for
+// c, 0 if the swap limit is not set.  By default, MemorySwap is set to twice
+// the size of RAM and -1 disables the swap limit.
+func memorySwapLimit(c *cgroups.Cgroup) int64 {
+	switch {
+	case c.MemorySwap > 0:
+		return c.MemorySwap
+	case c.MemorySwap == 0:
+		return c.Memory * 2
+	}
+	return 0
+}
+
+// limitValue returns the value that sets a memory limit, 0 removes the limit.
+func limitValue(limit int64) string {
+	if limit == 0 {
+		return "-1"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+func updateCpu(old, c *cgroups.Cgroup, dir string) error {
+	for _, v := range []struct {
+		file         string
+		old, current int64
+		def          int64
+	}{
+		{"cpu.shares", old.CpuShares, c.CpuShares, 1024},
+		{"cpu.cfs_period_us", old.CpuPeriod, c.CpuPeriod, 100000},
+		{"cpu.cfs_quota_us", old.CpuQuota, c.CpuQuota, -1},
+	} {
+		if v.current == v.old {
+			continue
+		}
+		if dir == "" {
+			return cgroups.NewNotFoundError("cpu")
+		}
+		value := v.current
+		if value == 0 {
+			value = v.def
+		}
+		if err := writeFile(dir, v.file, strconv.FormatInt(value, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateCpuset(old, c *cgroups.Cgroup, dir string) error {
+	for _, v := range []struct {
+		file         string
+		old, current string
+	}{
+		{"cpuset.cpus", old.CpusetCpus, c.CpusetCpus},
+		{"cpuset.mems", old.CpusetMems, c.CpusetMems},
+	} {
+		if v.current == v.old {
+			continue
+		}
+		if dir == "" {
+			return cgroups.NewNotFoundError("cpuset")
+		}
+		value := []byte(v.current)
+		// an empty value inherits the parent's setting like at creation
+		if v.current == "" {
+			parent, err := ioutil.ReadFile(filepath.Join(filepath.Dir(dir), v.file))
+			if err != nil {
+				return err
+			}
+			value = parent
+		}
+		if err := writeFile(dir, v.file, string(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateDevices changes the device rules of the cgroup.  Between two lists of allowed
+// devices only the removed rules are denied and the added rules allowed, denying every
+// device first would leave the container without access to any device, including
+// /dev/null, until the list is allowed again.
+func updateDevices(old, c *cgroups.Cgroup, dir string) error {
+	if c.AllowAllDevices == old.AllowAllDevices && reflect.DeepEqual(c.AllowedDevices, old.AllowedDevices) {
+		return nil
+	}
+	if dir == "" {
+		return cgroups.NewNotFoundError("devices")
+	}
+
+	if c.AllowAllDevices {
+		return writeFile(dir, "devices.allow", "a")
+	}
+
+	var (
+		current = deviceRules(c.AllowedDevices)
+		allowed = map[string]bool{}
+	)
+	// switching from allowing every device to a list of allowed devices can only be
+	// done by denying every device
+	if old.AllowAllDevices {
+		if err := writeFile(dir, "devices.deny", "a"); err != nil {
+			return err
+		}
+	} else {
+		allowed = deviceRules(old.AllowedDevices)
+	}
+
+	// the removed rules are denied first so that a rule that is both removed and
+	// added with different permissions ends up allowed
+	for rule := range allowed {
+		if !current[rule] {
+			if err := writeFile(dir, "devices.deny", rule); err != nil {
+				return err
+			}
+		}
+	}
+	for _, dev := range c.AllowedDevices {
+		rule := dev.GetCgroupAllowString()
+		if allowed[rule] {
+			continue
+		}
+		if err := writeFile(dir, "devices.allow", rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deviceRules returns the cgroup rules of the devices.
+func deviceRules(devs []*devices.Device) map[string]bool {
+	rules := make(map[string]bool, len(devs))
+	for _, dev := range devs {
+		rules[dev.GetCgroupAllowString()] = true
+	}
+	return rules
+}
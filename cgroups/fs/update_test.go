@@ -0,0 +1,120 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/devices"
+)
+
+func TestUpdateOnlyChangedValues(t *testing.T) {
+	helper := NewCgroupTestUtil("cpu", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"cpu.shares":        "512",
+		"cpu.cfs_period_us": "100000",
+		"cpu.cfs_quota_us":  "50000",
+	})
+
+	old := &cgroups.Cgroup{CpuShares: 512, CpuPeriod: 100000, CpuQuota: 50000}
+	updated := &cgroups.Cgroup{CpuShares: 512, CpuPeriod: 100000, CpuQuota: 0}
+
+	// the period is made invalid so that writing it would be noticed
+	helper.writeFileContents(map[string]string{"cpu.cfs_period_us": "unchanged"})
+
+	if err := Update(old, updated, map[string]string{"cpu": helper.CgroupPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		"cpu.shares":        "512",
+		"cpu.cfs_period_us": "unchanged",
+		"cpu.cfs_quota_us":  "-1",
+	} {
+		value, err := readFile(helper.CgroupPath, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Fatalf("expected %s to be %q but received %q", file, expected, value)
+		}
+	}
+}
+
+func TestUpdateMemory(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	old := &cgroups.Cgroup{Memory: 1024}
+	updated := &cgroups.Cgroup{Memory: 4096}
+
+	helper.writeFileContents(map[string]string{
+		"memory.limit_in_bytes":       "1024",
+		"memory.memsw.limit_in_bytes": "2048",
+	})
+	if err := Update(old, updated, map[string]string{"memory": helper.CgroupPath}); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"memory.limit_in_bytes":       "4096",
+		"memory.memsw.limit_in_bytes": "8192",
+	} {
+		value, err := readFile(helper.CgroupPath, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Fatalf("expected %s to be %q but received %q", file, expected, value)
+		}
+	}
+}
+
+func TestUpdateDevicesOnlyChangedRules(t *testing.T) {
+	helper := NewCgroupTestUtil("devices", t)
+	defer helper.cleanup()
+
+	var (
+		null   = &devices.Device{Type: 'c', MajorNumber: 1, MinorNumber: 3, CgroupPermissions: "rwm"}
+		zero   = &devices.Device{Type: 'c', MajorNumber: 1, MinorNumber: 5, CgroupPermissions: "rwm"}
+		random = &devices.Device{Type: 'c', MajorNumber: 1, MinorNumber: 8, CgroupPermissions: "rwm"}
+		old    = &cgroups.Cgroup{AllowedDevices: []*devices.Device{null, zero}}
+	)
+
+	helper.writeFileContents(map[string]string{
+		"devices.allow": "",
+		"devices.deny":  "",
+	})
+	if err := Update(old, &cgroups.Cgroup{AllowedDevices: []*devices.Device{null, random}}, map[string]string{"devices": helper.CgroupPath}); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"devices.deny":  "c 1:5 rwm",
+		"devices.allow": "c 1:8 rwm",
+	} {
+		value, err := readFile(helper.CgroupPath, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Fatalf("expected %s to be %q but received %q", file, expected, value)
+		}
+	}
+
+	// every device is denied when switching from allowing every device
+	if err := Update(&cgroups.Cgroup{AllowAllDevices: true}, old, map[string]string{"devices": helper.CgroupPath}); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := readFile(helper.CgroupPath, "devices.deny"); err != nil || value != "a" {
+		t.Fatalf("expected every device to be denied but received %q, %v", value, err)
+	}
+}
+
+func TestUpdateMissingSubsystem(t *testing.T) {
+	old := &cgroups.Cgroup{}
+	updated := &cgroups.Cgroup{CpusetCpus: "0"}
+
+	if err := Update(old, updated, map[string]string{}); !cgroups.IsNotFound(err) {
+		t.Fatalf("expected a not found error but received %v", err)
+	}
+}
@@ -27,3 +27,7 @@ func ApplyDevices(c *cgroups.Cgroup, pid int) error {
 func Freeze(c *cgroups.Cgroup, state cgroups.FreezerState) error {
 	return fmt.Errorf("Systemd not supported")
 }
+
+func Update(old, c *cgroups.Cgroup, paths map[string]string) error {
+	return fmt.Errorf("Systemd not supported")
+}
@@ -315,3 +315,33 @@ func joinCpuset(c *cgroups.Cgroup, pid int) error {
 
 	return s.SetDir(path, c.CpusetCpus, c.CpusetMems, pid)
 }
+
+// Update changes the limits of a running container.  The values supported by
+// systemd are set on the container's unit so that systemd does not revert them
+// when it re-applies the unit's cgroup context, all values are then written
+// to the cgroups like in the fs implementation.
+func Update(old, c *cgroups.Cgroup, paths map[string]string) error {
+	var properties []systemd.Property
+	if c.Memory != old.Memory {
+		// systemd removes the limit for the maximum value
+		limit := uint64(c.Memory)
+		if c.Memory == 0 {
+			limit = ^uint64(0)
+		}
+		properties = append(properties, newProp("MemoryLimit", limit))
+	}
+	if c.CpuShares != old.CpuShares {
+		shares := uint64(c.CpuShares)
+		if c.CpuShares == 0 {
+			shares = 1024
+		}
+		properties = append(properties, newProp("CPUShares", shares))
+	}
+	if len(properties) > 0 {
+		if err := theConn.SetUnitProperties(getUnitName(c), true, properties...); err != nil {
+			return err
+		}
+	}
+
+	return fs.Update(old, c, paths)
+}
@@ -1,6 +1,9 @@
 package libcontainer
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/mount"
 	"github.com/docker/libcontainer/network"
@@ -13,19 +16,25 @@ type Network network.Network
 type NamespaceType string
 
 const (
-	NEWNET  NamespaceType = "NEWNET"
-	NEWPID  NamespaceType = "NEWPID"
-	NEWNS   NamespaceType = "NEWNS"
-	NEWUTS  NamespaceType = "NEWUTS"
-	NEWIPC  NamespaceType = "NEWIPC"
-	NEWUSER NamespaceType = "NEWUSER"
+	NEWNET    NamespaceType = "NEWNET"
+	NEWPID    NamespaceType = "NEWPID"
+	NEWNS     NamespaceType = "NEWNS"
+	NEWUTS    NamespaceType = "NEWUTS"
+	NEWIPC    NamespaceType = "NEWIPC"
+	NEWUSER   NamespaceType = "NEWUSER"
+	NEWCGROUP NamespaceType = "NEWCGROUP"
 )
 
 // Namespace defines configuration for each namespace.  It specifies an
 // alternate path that is able to be joined via setns.
+//
+// Container references the namespace of another container of the same factory
+// by id.  The reference is resolved to the namespace file of the container's
+// current init process, and Path is set to it, when the container is started.
 type Namespace struct {
-	Type NamespaceType `json:"type"`
-	Path string        `json:"path,omitempty"`
+	Type      NamespaceType `json:"type"`
+	Path      string        `json:"path,omitempty"`
+	Container string        `json:"container,omitempty"`
 }
 
 type Namespaces []Namespace
@@ -63,6 +72,9 @@ func (n *Namespaces) Contains(t NamespaceType) bool {
 
 // Config defines configuration options for executing a process inside a contained environment.
 type Config struct {
+	// Version of the container.json format the config was written with.
+	Version int `json:"version,omitempty"`
+
 	// Mount specific options.
 	MountConfig *MountConfig `json:"mount_config,omitempty"`
 
@@ -87,10 +99,37 @@ type Config struct {
 	// and ensure that it is mounted inside the container's rootfs
 	Tty bool `json:"tty,omitempty"`
 
+	// BuiltinInit keeps libcontainer's init as PID 1 of the container's pid namespace.  The init
+	// forks the user's process, forwards signals to it, reaps orphaned processes and exits with
+	// the exit status of the user's process.  BuiltinInit requires the NEWPID namespace.
+	BuiltinInit bool `json:"builtin_init,omitempty"`
+
+	// StopSignal is the signal sent to the container's init process to stop the container
+	// gracefully, SIGTERM is used if it is not set
+	StopSignal int `json:"stop_signal,omitempty"`
+
+	// Rootless runs the container as an unprivileged user.  The container needs a NEWUSER
+	// namespace that maps the user's own uid and gid to the container's root, only loopback
+	// networks are supported, device nodes are bind mounted from the host and the cgroups
+	// are only applied if the user can write to the cgroup tree.
+	Rootless bool `json:"rootless,omitempty"`
+
 	// Namespaces specifies the container's namespaces that it should setup when cloning the init process
 	// If a namespace is not provided that namespace is shared from the container's parent process
 	Namespaces Namespaces `json:"namespaces,omitempty"`
 
+	// UidMappings and GidMappings map the user and group ids of the container to ids on the
+	// host.  They are required by the NEWUSER namespace and must map the container's root, the
+	// init process runs as the container's root until it switches to User.
+	UidMappings []IDMap `json:"uid_mappings,omitempty"`
+	GidMappings []IDMap `json:"gid_mappings,omitempty"`
+
+	// PersistNamespaces bind mounts the files of the container's namespaces into the ns directory
+	// of the container's directory when the init process is started.  The namespaces outlive the
+	// init process and their files can be joined via Namespace.Path until the container is
+	// destroyed.
+	PersistNamespaces bool `json:"persist_namespaces,omitempty"`
+
 	// Capabilities specify the capabilities to keep when executing the process inside the container
 	// All capbilities not specified will be dropped from the processes capability mask
 	Capabilities []string `json:"capabilities,omitempty"`
@@ -117,9 +156,57 @@ type Config struct {
 	// /proc/bus
 	RestrictSys bool `json:"restrict_sys,omitempty"`
 
+	// Sysctl sets the kernel parameters of the container's namespaces, keyed by their dotted
+	// names such as net.core.somaxconn.  Only the parameters of namespaces created for the
+	// container can be set, net.* requires NEWNET and the IPC parameters require NEWIPC.
+	Sysctl map[string]string `json:"sysctl,omitempty"`
+
 	// Rlimits specifies the resource limits, such as max open files, to set in the container
 	// If Rlimits are not set, the container will inherit rlimits from the parent process
 	Rlimits []Rlimit `json:"rlimits,omitempty"`
+
+	// Labels are arbitrary metadata of the container, for example its owner, that are
+	// used to select containers when listing them
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Hooks are commands executed on the host at specific points of the container's lifecycle
+	Hooks *Hooks `json:"hooks,omitempty"`
+}
+
+// IDMap maps a range of Size ids starting at ContainerID inside a user namespace
+// to the range starting at HostID outside of it.
+type IDMap struct {
+	ContainerID int `json:"container_id"`
+	HostID      int `json:"host_id"`
+	Size        int `json:"size"`
+}
+
+// HostUID returns the uid on the host of the container's root user.  The current
+// process' uid is returned when the container does not have a user namespace.
+func (c *Config) HostUID() (int, error) {
+	if !c.Namespaces.Contains(NEWUSER) {
+		return os.Getuid(), nil
+	}
+	return hostID(c.UidMappings, 0)
+}
+
+// HostGID returns the gid on the host of the container's root group.  The current
+// process' gid is returned when the container does not have a user namespace.
+func (c *Config) HostGID() (int, error) {
+	if !c.Namespaces.Contains(NEWUSER) {
+		return os.Getgid(), nil
+	}
+	return hostID(c.GidMappings, 0)
+}
+
+// hostID returns the host id that id inside the container is mapped to.
+func hostID(mappings []IDMap, id int) (int, error) {
+	for _, m := range mappings {
+		if id >= m.ContainerID && id < m.ContainerID+m.Size {
+			return m.HostID + id - m.ContainerID, nil
+		}
+	}
+	return -1, fmt.Errorf("id %d is not mapped in the user namespace", id)
 }
 
 // Routes can be specified to create entries in the route table as the container is started
@@ -3,33 +3,85 @@ NOTE: The API is in flux and mainly not implemented. Proceed with caution until
 */
 package libcontainer
 
+import (
+	"os"
+	"time"
+
+	"github.com/docker/libcontainer/cgroups"
+)
+
 // A libcontainer container object.
 //
-// Each container is thread-safe within the same process. Since a container can
+// Each container is thread-safe within the same process and operations on the
+// same container from different processes are serialized. Since a container can
 // be destroyed by a separate process, any function may return that the container
 // was not found.
 type Container interface {
 	// Returns the ID of the container
 	ID() string
 
-	// Returns the current run state of the container.
+	// Returns the current run state of the container.  The exit status of the init
+	// process of a STOPPED container is returned by ExitStatus and reported in the
+	// ContainerInfo returned by Factory.List.
 	//
 	// Errors:
 	// ContainerDestroyed - Container no longer exists,
 	// SystemError - System error.
 	RunState() (*RunState, Error)
 
+	// Returns how the init process of the container exited.  nil is returned if the
+	// container's init process was never started or is still running.
+	//
+	// Errors:
+	// ContainerDestroyed - Container no longer exists,
+	// SystemError - System error.
+	ExitStatus() (*ExitStatus, Error)
+
 	// Returns the current config of the container.
 	Config() *Config
 
-	// Start a process inside the container. Returns the PID of the new process (in the caller process's namespace) and a channel that will return the exit status of the process whenever it dies.
+	// Start a process inside the container.  If the container is STOPPED the process becomes the
+	// container's init process, otherwise it joins the running container.  The returned Process
+	// is used to wait for, signal and communicate with the new process.
+	//
+	// The namespaces of the config that reference other containers are joined when the init
+	// process is started, the referenced containers have to be running.
 	//
 	// Errors:
 	// ContainerDestroyed - Container no longer exists,
 	// ConfigInvalid - config is invalid,
 	// ContainerPaused - Container is paused,
+	// ContainerNotRunning - A container whose namespaces are joined is not running,
+	// SystemError - System error.
+	Start(config *ProcessConfig) (Process, Error)
+
+	// Changes the cgroup limits of the container.  The limits are applied to a running container
+	// and persisted in the container's config.  The name, parent and slice of the cgroup
+	// cannot be changed, the freezer state is changed by Pause and Resume.
+	//
+	// Errors:
+	// ContainerDestroyed - Container no longer exists,
+	// ConfigInvalid - cgroup is invalid,
+	// SystemError - System error.
+	Set(cgroup *cgroups.Cgroup) Error
+
+	// Sends a signal to the container's init process.
+	//
+	// Errors:
+	// ContainerDestroyed - Container no longer exists,
+	// ContainerNotRunning - Container is not running,
+	// SystemError - System error.
+	Signal(sig os.Signal) Error
+
+	// Stops the container.  The stop signal from the container's config is sent to the init process
+	// and, once the init process exited or after timeout, every remaining process in the
+	// container is killed and the container's cgroups are removed.  Stopping a STOPPED container
+	// does nothing.
+	//
+	// Errors:
+	// ContainerDestroyed - Container no longer exists,
 	// SystemError - System error.
-	Start(config *ProcessConfig) (pid int, exitChan chan int, err Error)
+	Stop(timeout time.Duration) Error
 
 	// Destroys the container after killing all running processes.
 	//
@@ -37,6 +89,7 @@ type Container interface {
 	// No error is returned if the container is already destroyed.
 	//
 	// Errors:
+	// ContainerInUse - The container's namespaces are joined by running containers,
 	// SystemError - System error.
 	Destroy() Error
 
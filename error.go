@@ -1,5 +1,7 @@
 package libcontainer
 
+import "syscall"
+
 // API error code type.
 type ErrorCode int
 
@@ -17,8 +19,36 @@ const (
 	// Common errors
 	ConfigInvalid
 	SystemError
+
+	// Codes are sent between processes and exposed to users so new codes are
+	// appended to keep the existing values stable.
+	ContainerNotRunning
+	ContainerInUse
 )
 
+func (c ErrorCode) String() string {
+	switch c {
+	case IdInUse:
+		return "Id already in use"
+	case InvalidIdFormat:
+		return "Invalid format"
+	case ContainerDestroyed:
+		return "Container destroyed"
+	case ContainerPaused:
+		return "Container paused"
+	case ContainerNotRunning:
+		return "Container not running"
+	case ContainerInUse:
+		return "Container in use"
+	case ConfigInvalid:
+		return "Invalid configuration"
+	case SystemError:
+		return "System error"
+	default:
+		return "Unknown error"
+	}
+}
+
 // API Error type.
 type Error interface {
 	error
@@ -34,4 +64,8 @@ type Error interface {
 
 	// Returns the error code for this error.
 	Code() ErrorCode
+
+	// Returns the errno of the failed system call which caused
+	// the error, or 0 if the error was not caused by a system call.
+	Errno() syscall.Errno
 }
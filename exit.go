@@ -0,0 +1,100 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/libcontainer/utils"
+)
+
+// The name of the file recording how the container's init process exited
+const exitFile = "exit.json"
+
+// ExitStatus records how the init process of a container exited.
+type ExitStatus struct {
+	// ExitCode is the exit code of the process, or -1 if it was terminated by a signal
+	ExitCode int `json:"exit_code"`
+
+	// Signal is the signal that terminated the process, 0 if the process exited
+	Signal int `json:"signal,omitempty"`
+
+	// CoreDumped is true if the process dumped a core when it was terminated
+	CoreDumped bool `json:"core_dumped,omitempty"`
+
+	// OOMKilled is true if the container's memory cgroup recorded an OOM kill
+	OOMKilled bool `json:"oom_killed,omitempty"`
+
+	// Rusage is the resource usage of the process and its waited for children
+	Rusage *Rusage `json:"rusage,omitempty"`
+
+	// Started is the time the process was started
+	Started time.Time `json:"started"`
+
+	// Finished is the time the process exit was collected
+	Finished time.Time `json:"finished"`
+}
+
+// String describes how the process exited, for example "exited 1" or "killed by
+// signal 9 (oom killed)".
+func (s *ExitStatus) String() string {
+	var (
+		desc  = fmt.Sprintf("exited %d", s.ExitCode)
+		notes []string
+	)
+	if s.Signal != 0 {
+		desc = fmt.Sprintf("killed by signal %d", s.Signal)
+	}
+	if s.CoreDumped {
+		notes = append(notes, "core dumped")
+	}
+	if s.OOMKilled {
+		notes = append(notes, "oom killed")
+	}
+	if len(notes) > 0 {
+		desc += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return desc
+}
+
+// Rusage is the resource usage of a process as returned by wait4.
+type Rusage struct {
+	UserTime               time.Duration `json:"user_time"`
+	SystemTime             time.Duration `json:"system_time"`
+	MaxRss                 int64         `json:"max_rss"` // Maximum resident set size (in kilobytes)
+	MinorFaults            int64         `json:"minor_faults"`
+	MajorFaults            int64         `json:"major_faults"`
+	InBlock                int64         `json:"in_block"`
+	OutBlock               int64         `json:"out_block"`
+	VoluntaryCtxSwitches   int64         `json:"voluntary_ctx_switches"`
+	InvoluntaryCtxSwitches int64         `json:"involuntary_ctx_switches"`
+}
+
+// SaveExitStatus writes the exit status of the container's init process to an
+// exit.json file in the specified path
+func SaveExitStatus(basePath string, status *ExitStatus) error {
+	return utils.WriteJSON(filepath.Join(basePath, exitFile), status)
+}
+
+// GetExitStatus reads the exit.json file of a stopped container
+func GetExitStatus(basePath string) (*ExitStatus, error) {
+	f, err := os.Open(filepath.Join(basePath, exitFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var status *ExitStatus
+	if err := json.NewDecoder(f).Decode(&status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// DeleteExitStatus deletes the exit.json file
+func DeleteExitStatus(basePath string) error {
+	return os.Remove(filepath.Join(basePath, exitFile))
+}
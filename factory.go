@@ -2,15 +2,15 @@ package libcontainer
 
 type Factory interface {
 
-	// Creates a new container with the given id and starts the initial process inside it.
+	// Creates a new container with the given id.  The initial process of the container is
+	// started by calling Start on the returned container.
+	//
 	// id must be a string containing only letters, digits and underscores and must contain
 	// between 1 and 1024 characters, inclusive.
 	//
 	// The id must not already be in use by an existing container. Containers created using
 	// a factory with the same path (and file system) must have distinct ids.
 	//
-	// Returns the new container with a running process.
-	//
 	// Errors:
 	// IdInUse - id is already in use by a container
 	// InvalidIdFormat - id has incorrect format
@@ -24,9 +24,24 @@ type Factory interface {
 	// from the state.
 	//
 	// Errors:
-	// Path does not exist
-	// Container is stopped
-	// System error
-	// TODO: fix description
+	// InvalidIdFormat - id has incorrect format
+	// ContainerDestroyed - no container exists with the given id
+	// SystemError - System error
 	Load(id string) (Container, Error)
+
+	// Returns the containers of the factory that are selected by filter, every container if
+	// filter is nil.  The containers are sorted by id.
+	//
+	// Errors:
+	// SystemError - System error
+	List(filter *ListFilter) ([]*ContainerInfo, Error)
+
+	// Reconcile destroys the containers whose init process died without cleaning up after
+	// itself, e.g. because the process that started the container crashed.  The processes
+	// left in the containers' cgroups are killed and the cgroups and host network interfaces
+	// of the containers are removed.  Returns the ids of the destroyed containers.
+	//
+	// Errors:
+	// SystemError - System error
+	Reconcile() ([]string, Error)
 }
@@ -0,0 +1,118 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// NewGenericError returns an Error wrapping err with the provided code.  The
+// stack of the caller is captured at the time the error is created.
+func NewGenericError(err error, c ErrorCode) Error {
+	return newGenericError(err, c, GetErrno(err))
+}
+
+// NewSystemError returns an Error for err.  If err already is an Error it is
+// returned unchanged so that the code of the original failure is preserved,
+// otherwise the error is wrapped with the SystemError code.
+func NewSystemError(err error) Error {
+	if le, ok := err.(Error); ok {
+		return le
+	}
+	return newGenericError(err, SystemError, GetErrno(err))
+}
+
+// NewSystemErrorWithCause returns an Error for err with its message prefixed by
+// cause.  The code and errno of err are preserved.
+func NewSystemErrorWithCause(err error, cause string) Error {
+	c := SystemError
+	if le, ok := err.(Error); ok {
+		c = le.Code()
+	}
+	return newGenericError(fmt.Errorf("%s: %s", cause, err), c, GetErrno(err))
+}
+
+// NewErrnoError returns an Error with the provided code for an errno that was
+// returned by a system call, possibly in another process.
+func NewErrnoError(message string, c ErrorCode, errno syscall.Errno) Error {
+	return newGenericError(fmt.Errorf("%s", message), c, errno)
+}
+
+func newGenericError(err error, c ErrorCode, errno syscall.Errno) Error {
+	return &genericError{
+		err:   err,
+		code:  c,
+		errno: errno,
+		stack: captureStack(),
+	}
+}
+
+type genericError struct {
+	err   error
+	code  ErrorCode
+	errno syscall.Errno
+	stack []byte
+}
+
+func (e *genericError) Error() string {
+	return e.err.Error()
+}
+
+func (e *genericError) Code() ErrorCode {
+	return e.code
+}
+
+func (e *genericError) Errno() syscall.Errno {
+	return e.errno
+}
+
+func (e *genericError) Stack() []byte {
+	return e.stack
+}
+
+func (e *genericError) Detail() string {
+	if e.errno != 0 {
+		return fmt.Sprintf("[%d] %s (errno %d): %s\n%s", e.code, e.code, e.errno, e.err, e.stack)
+	}
+	return fmt.Sprintf("[%d] %s: %s\n%s", e.code, e.code, e.err, e.stack)
+}
+
+// GetErrno returns the errno wrapped by the errors returned from the os and
+// syscall packages.  0 is returned if err was not caused by a failed system call.
+func GetErrno(err error) syscall.Errno {
+	switch e := err.(type) {
+	case syscall.Errno:
+		return e
+	case Error:
+		return e.Errno()
+	case *os.PathError:
+		return GetErrno(e.Err)
+	case *os.LinkError:
+		return GetErrno(e.Err)
+	case *os.SyscallError:
+		return GetErrno(e.Err)
+	case *exec.Error:
+		return GetErrno(e.Err)
+	}
+	return 0
+}
+
+// captureStack returns the stack trace of the goroutine that is creating
+// the error, skipping the frames inside of this file.
+func captureStack() []byte {
+	var stack []byte
+	for i := 3; ; i++ {
+		pc, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+		name := "???"
+		if f := runtime.FuncForPC(pc); f != nil {
+			name = f.Name()
+		}
+		stack = append(stack, fmt.Sprintf("%s\n\t%s:%d\n", name, file, line)...)
+	}
+	return stack
+}
@@ -0,0 +1,77 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestGenericErrorErrno(t *testing.T) {
+	_, err := os.Open("/non/existent/path")
+	if err == nil {
+		t.Fatal("expected error opening a non existent path")
+	}
+
+	lerr := NewSystemError(err)
+	if lerr.Code() != SystemError {
+		t.Fatalf("expected SystemError but received %s", lerr.Code())
+	}
+	if lerr.Errno() != syscall.ENOENT {
+		t.Fatalf("expected errno ENOENT but received %d", lerr.Errno())
+	}
+}
+
+func TestGenericErrorStack(t *testing.T) {
+	lerr := NewGenericError(fmt.Errorf("test error"), ConfigInvalid)
+
+	if !strings.Contains(string(lerr.Stack()), "TestGenericErrorStack") {
+		t.Fatalf("expected stack to contain the caller but received %s", lerr.Stack())
+	}
+	if strings.Contains(string(lerr.Stack()), "captureStack") {
+		t.Fatal("expected stack to not contain the frames of the error constructor")
+	}
+
+	detail := lerr.Detail()
+	if !strings.Contains(detail, "test error") || !strings.Contains(detail, ConfigInvalid.String()) {
+		t.Fatalf("expected detail to contain the message and code but received %s", detail)
+	}
+}
+
+func TestSystemErrorPreservesCode(t *testing.T) {
+	lerr := NewGenericError(syscall.EINVAL, ConfigInvalid)
+
+	if err := NewSystemError(lerr); err.Code() != ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid but received %s", err.Code())
+	}
+
+	err := NewSystemErrorWithCause(lerr, "setup networking")
+	if err.Code() != ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid but received %s", err.Code())
+	}
+	if err.Errno() != syscall.EINVAL {
+		t.Fatalf("expected errno EINVAL but received %d", err.Errno())
+	}
+	if err.Error() != "setup networking: invalid argument" {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+}
+
+func TestErrorCodesAreStable(t *testing.T) {
+	codes := map[ErrorCode]int{
+		IdInUse:             0,
+		InvalidIdFormat:     1,
+		ContainerDestroyed:  2,
+		ContainerPaused:     3,
+		ConfigInvalid:       4,
+		SystemError:         5,
+		ContainerNotRunning: 6,
+		ContainerInUse:      7,
+	}
+	for code, value := range codes {
+		if int(code) != value {
+			t.Fatalf("expected %s to be %d but it is %d", code, value, int(code))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package libcontainer
+
+// Hooks specifies the commands that are executed on the host at specific points
+// of the container's lifecycle.
+type Hooks struct {
+	// Prestart commands are executed after the container's cgroups, network and mounts
+	// are setup but before the user's process is executed inside the container.
+	Prestart []*Hook `json:"prestart,omitempty"`
+
+	// Poststart commands are executed after the user's process is started.
+	Poststart []*Hook `json:"poststart,omitempty"`
+
+	// Poststop commands are executed after the container's init process exited.  They
+	// are not executed when the container fails to start before or in the prestart hooks.
+	Poststop []*Hook `json:"poststop,omitempty"`
+}
+
+// Hook is a command executed on the host.  The container's State is written as
+// JSON to the command's stdin.
+type Hook struct {
+	// Path is the absolute path of the command to execute
+	Path string `json:"path,omitempty"`
+
+	// Args are the arguments passed to the command, not including the command's name
+	Args []string `json:"args,omitempty"`
+
+	// Env is the environment of the command, the environment of the caller is not inherited
+	Env []string `json:"env,omitempty"`
+
+	// Timeout is the number of seconds after which the command is killed and the hook fails.
+	// The command is not killed if the timeout is 0.
+	Timeout int `json:"timeout,omitempty"`
+}
@@ -0,0 +1,68 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Run executes the hook's command with the state written to its stdin.  An error
+// is returned if the command exits with a non zero status or does not finish
+// within the hook's timeout.
+func (h *Hook) Run(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(h.Path, h.Args...)
+	cmd.Env = h.Env
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// run the command in its own process group so that any children it spawns
+	// are killed with it on timeout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start hook %s: %s", h.Path, err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	if h.Timeout > 0 {
+		timeout = time.After(time.Duration(h.Timeout) * time.Second)
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			return fmt.Errorf("hook %s failed with %s: %s", h.Path, err, bytes.TrimSpace(output.Bytes()))
+		}
+	case <-timeout:
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-errc
+		return fmt.Errorf("hook %s timed out after %d seconds", h.Path, h.Timeout)
+	}
+	return nil
+}
+
+// RunHooks runs the hooks in order and stops at the first failure.
+func RunHooks(hooks []*Hook, state *State) error {
+	for _, h := range hooks {
+		if err := h.Run(state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
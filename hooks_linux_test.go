@@ -0,0 +1,71 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHookReceivesState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libcontainer-hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "state")
+	hook := &Hook{
+		Path: "/bin/sh",
+		Args: []string{"-c", "cat > " + out},
+	}
+	if err := hook.Run(&State{InitPid: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state *State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.InitPid != 42 {
+		t.Fatalf("expected init pid 42 but received %d", state.InitPid)
+	}
+}
+
+func TestHookFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libcontainer-hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "ran")
+	hooks := []*Hook{
+		{Path: "/bin/sh", Args: []string{"-c", "exit 1"}},
+		{Path: "/bin/sh", Args: []string{"-c", "touch " + out}},
+	}
+	if err := RunHooks(hooks, &State{}); err == nil {
+		t.Fatal("expected error running a failing hook")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("expected the hooks after the failing hook not to run")
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	hook := &Hook{
+		Path:    "/bin/sh",
+		Args:    []string{"-c", "sleep 10"},
+		Timeout: 1,
+	}
+	if err := hook.Run(&State{}); err == nil {
+		t.Fatal("expected error running a hook exceeding its timeout")
+	}
+}
@@ -0,0 +1,29 @@
+package libcontainer
+
+import "github.com/docker/libcontainer/user"
+
+// SubIDMappings returns the mappings of the subordinate id ranges allotted to a user
+// in /etc/subuid or /etc/subgid to consecutive ids inside the container starting at
+// containerID.  The user's own id is usually mapped to 0 and the ranges from 1.
+func SubIDMappings(ranges []user.SubID, containerID int) []IDMap {
+	maps := []IDMap{}
+	for _, r := range ranges {
+		if r.Count <= 0 {
+			continue
+		}
+		maps = append(maps, IDMap{ContainerID: containerID, HostID: r.SubID, Size: r.Count})
+		containerID += r.Count
+	}
+	return maps
+}
+
+// ValidateSubIDMappings checks that the host ids of the mappings are either ownID,
+// the id of the user itself, or fall within the ranges allotted to the user.
+func ValidateSubIDMappings(maps []IDMap, ranges []user.SubID, ownID int) error {
+	for _, m := range maps {
+		if err := user.ValidateSubIDMapping(m.ContainerID, m.HostID, m.Size, ranges, ownID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
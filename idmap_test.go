@@ -0,0 +1,35 @@
+package libcontainer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/libcontainer/user"
+)
+
+func TestSubIDMappings(t *testing.T) {
+	ranges := []user.SubID{{Name: "alice", SubID: 100000, Count: 65536}, {Name: "alice", SubID: 165536, Count: 1000}}
+
+	maps := append([]IDMap{{ContainerID: 0, HostID: 1000, Size: 1}}, SubIDMappings(ranges, 1)...)
+	expected := []IDMap{
+		{ContainerID: 0, HostID: 1000, Size: 1},
+		{ContainerID: 1, HostID: 100000, Size: 65536},
+		{ContainerID: 65537, HostID: 165536, Size: 1000},
+	}
+	if !reflect.DeepEqual(maps, expected) {
+		t.Fatalf("expected mappings %v but received %v", expected, maps)
+	}
+	if err := ValidateSubIDMappings(maps, ranges, 1000); err != nil {
+		t.Fatalf("expected the mappings to be valid but received %s", err)
+	}
+
+	// the mappings are used by the config as they are
+	config := &Config{Namespaces: Namespaces{{Type: NEWUSER}}, UidMappings: maps}
+	if uid, err := config.HostUID(); err != nil || uid != 1000 {
+		t.Fatalf("expected host uid 1000 but received %d, %v", uid, err)
+	}
+
+	if err := ValidateSubIDMappings([]IDMap{{ContainerID: 0, HostID: 999, Size: 2}}, ranges, 1000); err == nil {
+		t.Fatal("expected an error mapping ids that are not allotted to the user")
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/namespaces"
+	"github.com/docker/libcontainer/reexec"
 )
 
 func TestExecIn(t *testing.T) {
@@ -23,11 +24,13 @@ func TestExecIn(t *testing.T) {
 	defer remove(rootfs)
 
 	config := newTemplateConfig(rootfs)
-	if err := writeConfig(config); err != nil {
+	dataPath, err := newDataPath(config)
+	if err != nil {
 		t.Fatalf("failed to write config %s", err)
 	}
+	defer remove(dataPath)
 
-	containerCmd, statePath, containerErr := startLongRunningContainer(config)
+	containerCmd, statePath, containerErr := startLongRunningContainer(config, dataPath)
 	defer func() {
 		// kill the container
 		if containerCmd.Process != nil {
@@ -47,7 +50,7 @@ func TestExecIn(t *testing.T) {
 	execErr := make(chan error, 1)
 	go func() {
 		_, err := namespaces.ExecIn(config, state, []string{"ps"},
-			os.Args[0], "exec", buffers.Stdin, buffers.Stdout, buffers.Stderr,
+			reexec.Self(), "exec", buffers.Stdin, buffers.Stdout, buffers.Stderr,
 			"", nil)
 		execErr <- err
 	}()
@@ -73,11 +76,13 @@ func TestExecInRlimit(t *testing.T) {
 	defer remove(rootfs)
 
 	config := newTemplateConfig(rootfs)
-	if err := writeConfig(config); err != nil {
+	dataPath, err := newDataPath(config)
+	if err != nil {
 		t.Fatalf("failed to write config %s", err)
 	}
+	defer remove(dataPath)
 
-	containerCmd, statePath, containerErr := startLongRunningContainer(config)
+	containerCmd, statePath, containerErr := startLongRunningContainer(config, dataPath)
 	defer func() {
 		// kill the container
 		if containerCmd.Process != nil {
@@ -97,7 +102,7 @@ func TestExecInRlimit(t *testing.T) {
 	execErr := make(chan error, 1)
 	go func() {
 		_, err := namespaces.ExecIn(config, state, []string{"/bin/sh", "-c", "ulimit -n"},
-			os.Args[0], "exec", buffers.Stdin, buffers.Stdout, buffers.Stderr,
+			reexec.Self(), "exec", buffers.Stdin, buffers.Stdout, buffers.Stderr,
 			"", nil)
 		execErr <- err
 	}()
@@ -112,7 +117,7 @@ func TestExecInRlimit(t *testing.T) {
 }
 
 // start a long-running container so we have time to inspect execin processes
-func startLongRunningContainer(config *libcontainer.Config) (*exec.Cmd, string, chan error) {
+func startLongRunningContainer(config *libcontainer.Config, dataPath string) (*exec.Cmd, string, chan error) {
 	containerErr := make(chan error, 1)
 	containerCmd := &exec.Cmd{}
 	var statePath string
@@ -130,7 +135,7 @@ func startLongRunningContainer(config *libcontainer.Config) (*exec.Cmd, string,
 		buffers := newStdBuffers()
 		_, err := namespaces.Exec(config,
 			buffers.Stdin, buffers.Stdout, buffers.Stderr,
-			"", config.RootFs, []string{"sleep", "10"},
+			"", dataPath, []string{"sleep", "10"},
 			createCmd, containerStart.Done)
 		containerErr <- err
 	}()
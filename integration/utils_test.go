@@ -27,8 +27,8 @@ type stdBuffers struct {
 	Stderr *bytes.Buffer
 }
 
-func writeConfig(config *libcontainer.Config) error {
-	f, err := os.OpenFile(filepath.Join(config.RootFs, "container.json"), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0700)
+func writeConfig(dataPath string, config *libcontainer.Config) error {
+	f, err := os.OpenFile(filepath.Join(dataPath, "container.json"), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0700)
 	if err != nil {
 		return err
 	}
@@ -36,18 +36,18 @@ func writeConfig(config *libcontainer.Config) error {
 	return json.NewEncoder(f).Encode(config)
 }
 
-func loadConfig() (*libcontainer.Config, error) {
-	f, err := os.Open(filepath.Join(os.Getenv("data_path"), "container.json"))
+// newDataPath creates a new tmp directory holding the container.json and the state
+// of the container so that nothing is written to the source tree
+func newDataPath(config *libcontainer.Config) (string, error) {
+	dir, err := ioutil.TempDir("", "libcontainer-data")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	defer f.Close()
-
-	var container *libcontainer.Config
-	if err := json.NewDecoder(f).Decode(&container); err != nil {
-		return nil, err
+	if err := writeConfig(dir, config); err != nil {
+		remove(dir)
+		return "", err
 	}
-	return container, nil
+	return dir, nil
 }
 
 // newRootFs creates a new tmp directory and copies the busybox root filesystem
@@ -60,7 +60,8 @@ func newRootFs() (string, error) {
 		return "", err
 	}
 	if err := copyBusybox(dir); err != nil {
-		return "", nil
+		remove(dir)
+		return "", err
 	}
 	return dir, nil
 }
@@ -84,12 +85,14 @@ func copyBusybox(dest string) error {
 // buffers are returned containing the STDOUT and STDERR output for the run
 // along with the exit code and any go error
 func runContainer(config *libcontainer.Config, console string, args ...string) (buffers *stdBuffers, exitCode int, err error) {
-	if err := writeConfig(config); err != nil {
+	dataPath, err := newDataPath(config)
+	if err != nil {
 		return nil, -1, err
 	}
+	defer remove(dataPath)
 
 	buffers = newStdBuffers()
 	exitCode, err = namespaces.Exec(config, buffers.Stdin, buffers.Stdout, buffers.Stderr,
-		console, config.RootFs, args, namespaces.DefaultCreateCommand, nil)
+		console, dataPath, args, namespaces.DefaultCreateCommand, nil)
 	return
 }
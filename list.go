@@ -0,0 +1,65 @@
+package libcontainer
+
+import "time"
+
+// ContainerInfo describes a container returned by Factory.List.
+type ContainerInfo struct {
+	// ID of the container
+	ID string `json:"id"`
+
+	// State is the run state of the container when it was listed
+	State RunState `json:"state"`
+
+	// InitPid is the pid of the container's init process, 0 if the container is stopped
+	InitPid int `json:"init_pid,omitempty"`
+
+	// ExitStatus records how the init process of a stopped container exited, nil if
+	// the container is running or was never started
+	ExitStatus *ExitStatus `json:"exit_status,omitempty"`
+
+	// Created is the time the container was created
+	Created time.Time `json:"created"`
+
+	// RootFs, Hostname and Labels are copied from the container's config
+	RootFs   string            `json:"root_fs,omitempty"`
+	Hostname string            `json:"hostname,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// ListFilter selects the containers returned by Factory.List.  A nil filter
+// selects every container.
+type ListFilter struct {
+	// States selects the containers in one of the run states, containers in any
+	// state are selected if States is empty
+	States []RunState
+
+	// Labels selects the containers that have every label with the same value
+	Labels map[string]string
+}
+
+// Matches reports whether the container described by info is selected by the filter.
+func (f *ListFilter) Matches(info *ContainerInfo) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.States) > 0 {
+		found := false
+		for _, s := range f.States {
+			if s == info.State {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for k, v := range f.Labels {
+		if value, ok := info.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package libcontainer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// The versions of the container.json and state.json formats written by this
+// version of libcontainer.  Documents written before the formats were versioned
+// do not have a version field and are treated as version 0.
+const (
+	ConfigVersion = 1
+	StateVersion  = 1
+)
+
+// migration upgrades a decoded document from one version to the next.
+type migration func(doc map[string]interface{}) error
+
+// configMigrations and stateMigrations are indexed by the version they upgrade from.
+var (
+	configMigrations = []migration{
+		0: migrateConfigV0,
+	}
+	// states written before the format was versioned have the layout of version 1
+	stateMigrations = []migration{
+		0: nil,
+	}
+)
+
+// LoadConfig decodes a container.json document from r.  Documents written by
+// older versions of libcontainer are upgraded to ConfigVersion.
+func LoadConfig(r io.Reader) (*Config, error) {
+	var config *Config
+	if err := decodeVersioned(r, ConfigVersion, configMigrations, &config); err != nil {
+		return nil, fmt.Errorf("load config: %s", err)
+	}
+	return config, nil
+}
+
+// LoadState decodes a state.json document from r.  The layout of the state did
+// not change since it was versioned, older documents only get StateVersion set.
+func LoadState(r io.Reader) (*State, error) {
+	var state *State
+	if err := decodeVersioned(r, StateVersion, stateMigrations, &state); err != nil {
+		return nil, fmt.Errorf("load state: %s", err)
+	}
+	return state, nil
+}
+
+// decodeVersioned decodes the document in r into v.  Documents at the current
+// version are decoded directly, older documents are decoded into a map that is
+// upgraded by the migrations.  The numbers of the map are kept as json.Number so
+// that large values like RLIM_INFINITY survive the migration.
+func decodeVersioned(r io.Reader, current int, migrations []migration, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var probe struct {
+		Version json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+
+	version := 0
+	if len(probe.Version) > 0 {
+		if version, err = strconv.Atoi(string(probe.Version)); err != nil || version < 0 {
+			return fmt.Errorf("invalid version %s", probe.Version)
+		}
+	}
+	if version > current {
+		return fmt.Errorf("version %d is newer than the supported version %d", version, current)
+	}
+	if version == current {
+		return json.Unmarshal(data, v)
+	}
+
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+
+	for ; version < current; version++ {
+		// a nil migration means the layout did not change
+		if migrations[version] == nil {
+			continue
+		}
+		if err := migrations[version](doc); err != nil {
+			return fmt.Errorf("migrate from version %d: %s", version, err)
+		}
+	}
+	doc["version"] = current
+
+	if data, err = json.Marshal(doc); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// migrateConfigV0 upgrades configs written before the format was versioned.
+//
+// Namespaces used to be a map of the namespace name to whether it is enabled and
+// the veth settings of a network used to be stored in its context map.
+func migrateConfigV0(doc map[string]interface{}) error {
+	if namespaces, ok := doc["namespaces"].(map[string]interface{}); ok {
+		names := []string{}
+		for name, enabled := range namespaces {
+			if b, ok := enabled.(bool); ok && b {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		list := []interface{}{}
+		for _, name := range names {
+			list = append(list, map[string]interface{}{"type": name})
+		}
+		doc["namespaces"] = list
+	}
+
+	networks, _ := doc["networks"].([]interface{})
+	for _, n := range networks {
+		network, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		context, ok := network["context"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for old, current := range map[string]string{
+			"bridge": "bridge",
+			"prefix": "veth_prefix",
+		} {
+			if value, exists := context[old]; exists {
+				if _, set := network[current]; !set {
+					network[current] = value
+				}
+			}
+		}
+		delete(network, "context")
+	}
+	return nil
+}
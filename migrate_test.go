@@ -0,0 +1,131 @@
+package libcontainer
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigMigratesV0(t *testing.T) {
+	config, err := LoadConfig(strings.NewReader(`{
+		"hostname": "koye",
+		"namespaces": {"NEWNET": true, "NEWPID": true, "NEWUSER": false},
+		"networks": [
+			{"type": "veth", "context": {"bridge": "docker0", "prefix": "veth"}, "mtu": 1500}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Version != ConfigVersion {
+		t.Fatalf("expected version %d but received %d", ConfigVersion, config.Version)
+	}
+	if config.Hostname != "koye" {
+		t.Fatalf("expected hostname koye but received %q", config.Hostname)
+	}
+	if len(config.Namespaces) != 2 || !config.Namespaces.Contains(NEWNET) || !config.Namespaces.Contains(NEWPID) {
+		t.Fatalf("expected NEWNET and NEWPID namespaces but received %v", config.Namespaces)
+	}
+	if len(config.Networks) != 1 {
+		t.Fatalf("expected 1 network but received %d", len(config.Networks))
+	}
+	if n := config.Networks[0]; n.Bridge != "docker0" || n.VethPrefix != "veth" || n.Mtu != 1500 {
+		t.Fatalf("expected the network context to be migrated but received %+v", n)
+	}
+}
+
+func TestLoadConfigCurrentVersion(t *testing.T) {
+	container, err := loadConfig("minimal.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open("sample_configs/minimal.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	config, err := LoadConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Namespaces) != len(container.Namespaces) || len(config.Capabilities) != len(container.Capabilities) {
+		t.Fatal("expected a config without a version to load unchanged")
+	}
+}
+
+func TestLoadConfigLargeNumbers(t *testing.T) {
+	for _, version := range []string{"", `"version": 1,`} {
+		config, err := LoadConfig(strings.NewReader(`{` + version + `
+			"rlimits": [{"type": 7, "hard": 18446744073709551615, "soft": 18446744073709551615}],
+			"cgroups": {"memory": 9007199254740993}
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(config.Rlimits) != 1 || config.Rlimits[0].Hard != math.MaxUint64 || config.Rlimits[0].Soft != math.MaxUint64 {
+			t.Fatalf("expected the RLIM_INFINITY rlimit to be preserved but received %+v", config.Rlimits)
+		}
+		if config.Cgroups.Memory != 9007199254740993 {
+			t.Fatalf("expected the memory limit to be preserved but received %d", config.Cgroups.Memory)
+		}
+	}
+}
+
+func TestLoadConfigInvalidVersion(t *testing.T) {
+	for _, version := range []string{`"1"`, `1.5`, `-1`} {
+		if _, err := LoadConfig(strings.NewReader(`{"version": ` + version + `}`)); err == nil {
+			t.Fatalf("expected error loading a config with version %s", version)
+		}
+	}
+}
+
+func TestLoadConfigNewerVersion(t *testing.T) {
+	if _, err := LoadConfig(strings.NewReader(`{"version": 1000}`)); err == nil {
+		t.Fatal("expected error loading a config with a newer version")
+	}
+}
+
+func TestSaveAndGetState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libcontainer-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a state.json written before the format was versioned
+	v0 := `{"init_pid": 10, "init_start_time": "1234", "network_state": {"veth_host": "veth0"}, "cgroup_paths": {"memory": "/sys/fs/cgroup/memory/test"}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "state.json"), []byte(v0), 0600); err != nil {
+		t.Fatal(err)
+	}
+	state, err := GetState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != StateVersion || state.InitPid != 10 || state.InitStartTime != "1234" {
+		t.Fatalf("expected the state to be migrated but received %+v", state)
+	}
+	if state.NetworkState.VethHost != "veth0" || state.CgroupPaths["memory"] != "/sys/fs/cgroup/memory/test" {
+		t.Fatalf("expected the network state and cgroup paths to be kept but received %+v", state)
+	}
+
+	saved := &State{InitPid: 20}
+	if err := SaveState(dir, saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Version != 0 {
+		t.Fatalf("expected the caller's state to be left untouched but received version %d", saved.Version)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":1`) {
+		t.Fatalf("expected the saved state to have a version but received %s", data)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 
+	dockermount "github.com/docker/docker/pkg/mount"
 	"github.com/docker/libcontainer/label"
 	"github.com/docker/libcontainer/mount/nodes"
+	"github.com/docker/libcontainer/system"
 )
 
 // default mount point flags
@@ -28,15 +31,19 @@ type mount struct {
 func InitializeMountNamespace(rootfs, console string, sysReadonly bool, mountConfig *MountConfig) error {
 	var (
 		err  error
-		flag = syscall.MS_PRIVATE
+		flag = rootfsPropagation(mountConfig)
 	)
 
-	if mountConfig.NoPivotRoot {
-		flag = syscall.MS_SLAVE
+	if err := syscall.Mount("", "/", "", uintptr(flag), ""); err != nil {
+		return fmt.Errorf("mounting / with flags %X %s", flag, err)
 	}
 
-	if err := syscall.Mount("", "/", "", uintptr(flag|syscall.MS_REC), ""); err != nil {
-		return fmt.Errorf("mounting / with flags %X %s", (flag | syscall.MS_REC), err)
+	// pivot_root and MS_MOVE fail when the parent of the rootfs is shared so the
+	// parent is made a slave, which the bind mount of the rootfs inherits
+	if flag&syscall.MS_SHARED != 0 {
+		if err := rootfsParentMountSlave(rootfs); err != nil {
+			return err
+		}
 	}
 
 	if err := syscall.Mount(rootfs, rootfs, "bind", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
@@ -86,6 +93,14 @@ func InitializeMountNamespace(rootfs, console string, sysReadonly bool, mountCon
 		return err
 	}
 
+	// the mounts of the container are shared with each other once the rootfs is the
+	// container's root, they remain slaves of the host's mounts
+	if flag&syscall.MS_SHARED != 0 {
+		if err := syscall.Mount("", "/", "", uintptr(flag), ""); err != nil {
+			return fmt.Errorf("mounting / with flags %X %s", flag, err)
+		}
+	}
+
 	if mountConfig.ReadonlyFs {
 		if err := SetReadonly(); err != nil {
 			return fmt.Errorf("set readonly %s", err)
@@ -97,6 +112,42 @@ func InitializeMountNamespace(rootfs, console string, sysReadonly bool, mountCon
 	return nil
 }
 
+// rootfsPropagation returns the mount flags of the rootfs propagation mode.  The
+// rootfs is rprivate by default, or rslave with NoPivotRoot as the host's mounts are
+// not unmounted from a container that does not pivot its root and must follow the
+// host's unmounts.
+func rootfsPropagation(mountConfig *MountConfig) int {
+	if flag, ok := PropagationFlags[mountConfig.RootfsPropagation]; ok {
+		return flag
+	}
+	if mountConfig.NoPivotRoot {
+		return syscall.MS_SLAVE | syscall.MS_REC
+	}
+	return syscall.MS_PRIVATE | syscall.MS_REC
+}
+
+// rootfsParentMountSlave makes the mount holding the rootfs a slave so that it still
+// receives the host's mounts but the container's mounts do not propagate to the host.
+// The mount is only changed within the container's mount namespace.
+func rootfsParentMountSlave(rootfs string) error {
+	mounts, err := dockermount.GetMounts()
+	if err != nil {
+		return err
+	}
+
+	parent := "/"
+	for _, m := range mounts {
+		if (rootfs == m.Mountpoint || strings.HasPrefix(rootfs, m.Mountpoint+"/")) && len(m.Mountpoint) > len(parent) {
+			parent = m.Mountpoint
+		}
+	}
+
+	if err := syscall.Mount("", parent, "", syscall.MS_SLAVE, ""); err != nil {
+		return fmt.Errorf("mounting %s as slave %s", parent, err)
+	}
+	return nil
+}
+
 // mountSystem sets up linux specific system mounts like mqueue, sys, proc, shm, and devpts
 // inside the mount namespace
 func mountSystem(rootfs string, sysReadonly bool, mountConfig *MountConfig) error {
@@ -105,12 +156,37 @@ func mountSystem(rootfs string, sysReadonly bool, mountConfig *MountConfig) erro
 			return fmt.Errorf("mkdirall %s %s", m.path, err)
 		}
 		if err := syscall.Mount(m.source, m.path, m.device, uintptr(m.flags), m.data); err != nil {
-			return fmt.Errorf("mounting %s into %s %s", m.source, m.path, err)
+			if system.RunningInUserNS() {
+				err = mountInUserNS(m, err)
+			}
+			if err != nil {
+				return fmt.Errorf("mounting %s into %s %s", m.source, m.path, err)
+			}
 		}
 	}
 	return nil
 }
 
+// mountInUserNS retries a system mount that failed with err in a user namespace.
+// sysfs can only be mounted with a network namespace owned by the user namespace,
+// the host's /sys is bind mounted otherwise.  devpts fails when the tty group is
+// not mapped and is mounted without it.
+func mountInUserNS(m mount, err error) error {
+	switch {
+	case m.device == "sysfs" && err == syscall.EPERM:
+		if err := syscall.Mount("/sys", m.path, "bind", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return err
+		}
+		if m.flags&syscall.MS_RDONLY != 0 {
+			return syscall.Mount(m.path, m.path, "bind", uintptr(m.flags|syscall.MS_BIND|syscall.MS_REMOUNT), "")
+		}
+		return nil
+	case m.device == "devpts" && err == syscall.EINVAL:
+		return syscall.Mount(m.source, m.path, m.device, uintptr(m.flags), strings.Replace(m.data, ",gid=5", "", 1))
+	}
+	return err
+}
+
 func createIfNotExists(path string, isDir bool) error {
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
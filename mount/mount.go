@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/label"
 )
 
@@ -16,8 +18,25 @@ type Mount struct {
 	Destination string `json:"destination,omitempty"` // Destination path, in the container
 	Writable    bool   `json:"writable,omitempty"`
 	Relabel     string `json:"relabel,omitempty"` // Relabel source if set, "z" indicates shared, "Z" indicates unshared
-	Private     bool   `json:"private,omitempty"`
-	Slave       bool   `json:"slave,omitempty"`
+	Private     bool   `json:"private,omitempty"` // Deprecated, use a "private" Propagation
+	Slave       bool   `json:"slave,omitempty"`   // Deprecated, use a "slave" Propagation
+
+	// Propagation is the propagation mode of the mount, one of private, rprivate, slave,
+	// rslave, shared or rshared.  The mount keeps the propagation inherited from the rootfs
+	// if it is not set.  Mounts made on the host below a slave or shared mount only show up
+	// in the container if the rootfs propagation is slave or shared as well.
+	Propagation string `json:"propagation,omitempty"`
+}
+
+// PropagationFlags maps the propagation modes of the mounts and the rootfs to their
+// mount flags.
+var PropagationFlags = map[string]int{
+	"private":  syscall.MS_PRIVATE,
+	"rprivate": syscall.MS_PRIVATE | syscall.MS_REC,
+	"slave":    syscall.MS_SLAVE,
+	"rslave":   syscall.MS_SLAVE | syscall.MS_REC,
+	"shared":   syscall.MS_SHARED,
+	"rshared":  syscall.MS_SHARED | syscall.MS_REC,
 }
 
 func (m *Mount) Mount(rootfs, mountLabel string) error {
@@ -26,6 +45,8 @@ func (m *Mount) Mount(rootfs, mountLabel string) error {
 		return m.bindMount(rootfs, mountLabel)
 	case "tmpfs":
 		return m.tmpfsMount(rootfs, mountLabel)
+	case "cgroup":
+		return m.cgroupMount(rootfs, mountLabel)
 	default:
 		return fmt.Errorf("unsupported mount type %s for %s", m.Type, m.Destination)
 	}
@@ -41,10 +62,6 @@ func (m *Mount) bindMount(rootfs, mountLabel string) error {
 		flags = flags | syscall.MS_RDONLY
 	}
 
-	if m.Slave {
-		flags = flags | syscall.MS_SLAVE
-	}
-
 	stat, err := os.Stat(m.Source)
 	if err != nil {
 		return err
@@ -76,13 +93,7 @@ func (m *Mount) bindMount(rootfs, mountLabel string) error {
 		}
 	}
 
-	if m.Private {
-		if err := syscall.Mount("", dest, "none", uintptr(syscall.MS_PRIVATE), ""); err != nil {
-			return fmt.Errorf("mounting %s private %s", dest, err)
-		}
-	}
-
-	return nil
+	return m.setPropagation(dest)
 }
 
 func (m *Mount) tmpfsMount(rootfs, mountLabel string) error {
@@ -105,5 +116,94 @@ func (m *Mount) tmpfsMount(rootfs, mountLabel string) error {
 		return fmt.Errorf("%s mounting %s in tmpfs", err, dest)
 	}
 
+	return m.setPropagation(dest)
+}
+
+// cgroupMount mounts a tmpfs at the destination holding a mount of every cgroup
+// hierarchy of the host, named after the host's mount point.  The hierarchies are
+// mounted within the container's cgroup namespace so that their root is the
+// container's cgroup.
+func (m *Mount) cgroupMount(rootfs, mountLabel string) error {
+	var (
+		err   error
+		flags = defaultMountFlags
+		dest  = filepath.Join(rootfs, m.Destination)
+	)
+
+	if !m.Writable {
+		flags = flags | syscall.MS_RDONLY
+	}
+
+	// FIXME: (crosbymichael) This does not belong here and should be done a layer above
+	if dest, err = symlink.FollowSymlinkInScope(dest, rootfs); err != nil {
+		return err
+	}
+
+	if err := createIfNotExists(dest, true); err != nil {
+		return fmt.Errorf("creating new cgroup mount target %s", err)
+	}
+
+	mounts, err := cgroups.GetCgroupMounts()
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Mount("tmpfs", dest, "tmpfs", uintptr(defaultMountFlags), label.FormatMountLabel("mode=755", mountLabel)); err != nil {
+		return fmt.Errorf("%s mounting %s in tmpfs", err, dest)
+	}
+
+	for _, cm := range mounts {
+		if len(cm.Subsystems) == 0 {
+			continue
+		}
+
+		var (
+			subsystems = strings.Join(cm.Subsystems, ",")
+			path       = filepath.Join(dest, filepath.Base(cm.Mountpoint))
+		)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+		if err := syscall.Mount("cgroup", path, "cgroup", uintptr(flags), subsystems); err != nil {
+			return fmt.Errorf("mounting cgroup %s into %s %s", subsystems, path, err)
+		}
+	}
+
+	// the tmpfs is made readonly once the hierarchies are mounted
+	if !m.Writable {
+		if err := syscall.Mount("tmpfs", dest, "tmpfs", uintptr(defaultMountFlags|syscall.MS_RDONLY|syscall.MS_REMOUNT), ""); err != nil {
+			return fmt.Errorf("remounting %s readonly %s", dest, err)
+		}
+	}
+
+	return m.setPropagation(dest)
+}
+
+// propagation returns the mount flags of the mount's propagation mode, the deprecated
+// Private and Slave fields are used when no mode is set.  0 is returned if the mount
+// keeps its inherited propagation.
+func (m *Mount) propagation() int {
+	switch {
+	case m.Propagation != "":
+		return PropagationFlags[m.Propagation]
+	case m.Private:
+		return syscall.MS_PRIVATE
+	case m.Slave:
+		return syscall.MS_SLAVE
+	}
+	return 0
+}
+
+// setPropagation changes the propagation of the mount at dest.  The propagation flags
+// are ignored by the kernel when they are combined with other flags so they are applied
+// with a mount call of their own.
+func (m *Mount) setPropagation(dest string) error {
+	flags := m.propagation()
+	if flags == 0 {
+		return nil
+	}
+	if err := syscall.Mount("", dest, "none", uintptr(flags), ""); err != nil {
+		return fmt.Errorf("changing the propagation of %s with flags %X %s", dest, flags, err)
+	}
 	return nil
 }
@@ -13,6 +13,12 @@ type MountConfig struct {
 	// This is a common option when the container is running in ramdisk
 	NoPivotRoot bool `json:"no_pivot_root,omitempty"`
 
+	// RootfsPropagation is the propagation mode of the container's mounts, one of private,
+	// rprivate, slave, rslave, shared or rshared.  It defaults to rprivate, or to rslave with
+	// NoPivotRoot.  With rslave or rshared, mounts made on the host after the container was
+	// started, like automounts, show up in the container.
+	RootfsPropagation string `json:"rootfs_propagation,omitempty"`
+
 	// ReadonlyFs will remount the container's rootfs as readonly where only externally mounted
 	// bind mounts are writtable
 	ReadonlyFs bool `json:"readonly_fs,omitempty"`
@@ -9,6 +9,7 @@ import (
 	"syscall"
 
 	"github.com/docker/libcontainer/devices"
+	"github.com/docker/libcontainer/system"
 )
 
 // Create the device nodes in the container.
@@ -46,6 +47,11 @@ func CreateDeviceNode(rootfs string, node *devices.Device) error {
 	}
 
 	if err := syscall.Mknod(dest, uint32(fileMode), devices.Mkdev(node.MajorNumber, node.MinorNumber)); err != nil && !os.IsExist(err) {
+		// device nodes cannot be created in a user namespace, the host's node is
+		// bind mounted instead and keeps its ownership
+		if err == syscall.EPERM && system.RunningInUserNS() {
+			return bindDeviceNode(dest, node)
+		}
 		return fmt.Errorf("mknod %s %s", node.Path, err)
 	}
 
@@ -55,3 +61,17 @@ func CreateDeviceNode(rootfs string, node *devices.Device) error {
 
 	return nil
 }
+
+// bindDeviceNode bind mounts the device node at the node's path on the host to dest.
+func bindDeviceNode(dest string, node *devices.Device) error {
+	f, err := os.OpenFile(dest, os.O_CREATE, 0755)
+	if err != nil {
+		return fmt.Errorf("create %s %s", node.Path, err)
+	}
+	f.Close()
+
+	if err := syscall.Mount(node.Path, dest, "bind", syscall.MS_BIND, ""); err != nil {
+		return fmt.Errorf("bind mount %s %s", node.Path, err)
+	}
+	return nil
+}
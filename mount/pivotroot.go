@@ -26,6 +26,13 @@ func PivotRoot(rootfs string) error {
 
 	// path to pivot dir now changed, update
 	pivotDir = filepath.Join("/", filepath.Base(pivotDir))
+
+	// the old root is made a slave so that unmounting it does not propagate to the
+	// host when the rootfs propagation is shared
+	if err := syscall.Mount("", pivotDir, "", syscall.MS_SLAVE|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("mounting %s as slave %s", pivotDir, err)
+	}
+
 	if err := syscall.Unmount(pivotDir, syscall.MNT_DETACH); err != nil {
 		return fmt.Errorf("unmount pivot_root dir %s", err)
 	}
@@ -6,6 +6,31 @@ import (
 	"syscall"
 )
 
+// lockedMountFlags maps the statfs flags of a mount to the mount flags that are
+// locked when the mount is inherited by a less privileged user namespace.
+var lockedMountFlags = map[int64]uintptr{
+	1:    syscall.MS_RDONLY,     // ST_RDONLY
+	2:    syscall.MS_NOSUID,     // ST_NOSUID
+	4:    syscall.MS_NODEV,      // ST_NODEV
+	8:    syscall.MS_NOEXEC,     // ST_NOEXEC
+	1024: syscall.MS_NOATIME,    // ST_NOATIME
+	2048: syscall.MS_NODIRATIME, // ST_NODIRATIME
+	4096: syscall.MS_RELATIME,   // ST_RELATIME
+}
+
+// SetReadonly remounts the root filesystem read only.  The flags of the current
+// mount are kept as a user namespace cannot clear them.
 func SetReadonly() error {
-	return syscall.Mount("/", "/", "bind", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_REC, "")
+	var st syscall.Statfs_t
+	if err := syscall.Statfs("/", &st); err != nil {
+		return err
+	}
+
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | syscall.MS_REC)
+	for stFlag, mountFlag := range lockedMountFlags {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+	return syscall.Mount("/", "/", "bind", flags, "")
 }
@@ -0,0 +1,93 @@
+// +build linux
+
+package namespaces
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// runBuiltinInit forks the user's process and stays as PID 1 of the container's
+// pid namespace.  Signals received by the init are forwarded to the user's
+// process and every child of the init is reaped, including orphaned processes
+// that were started with ExecIn.  The init exits with the exit status of the
+// user's process, the kernel then kills the remaining processes of the namespace.
+//
+// The sync pipe is closed once the user's process is started, errors starting
+// the process are returned to Init so that they are sent to the parent.
+func runBuiltinInit(args []string, tty bool, pipe *os.File) error {
+	name, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	// signals are handled before the user's process is started so that its
+	// SIGCHLD cannot be missed
+	sigc := make(chan os.Signal, 128)
+	signal.Notify(sigc)
+
+	process, err := os.StartProcess(name, args, &os.ProcAttr{
+		Env:   os.Environ(),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys: &syscall.SysProcAttr{
+			// the user's process gets its own process group which is made the
+			// foreground group of the console so that it receives the signals
+			// generated by the terminal instead of the init
+			Setpgid:    true,
+			Foreground: tty,
+			Ctty:       0,
+		},
+	})
+	if err != nil {
+		signal.Stop(sigc)
+		return err
+	}
+	pipe.Close()
+
+	for sig := range sigc {
+		switch sig {
+		case syscall.SIGCHLD:
+			if status, exited := reapChildren(process.Pid); exited {
+				os.Exit(exitCode(status))
+			}
+		case syscall.SIGURG:
+			// used by the go runtime to preempt goroutines
+		default:
+			process.Signal(sig)
+		}
+	}
+	panic("unreachable")
+}
+
+// reapChildren collects the exit status of every child that exited.  The status
+// of the process with pid is returned once it exited.
+func reapChildren(pid int) (syscall.WaitStatus, bool) {
+	var (
+		status syscall.WaitStatus
+		result syscall.WaitStatus
+		exited bool
+	)
+	for {
+		wpid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || wpid <= 0 {
+			return result, exited
+		}
+		if wpid == pid {
+			result, exited = status, true
+		}
+	}
+}
+
+// exitCode returns the exit code of a process, processes killed by a signal
+// exit with 128 plus the signal number like in a shell.
+func exitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package namespaces
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReapChildren(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// the first child stands in for an orphan that is reaped by the init
+	orphan, err := os.StartProcess(sh, []string{"sh", "-c", "exit 0"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	process, err := os.StartProcess(sh, []string{"sh", "-c", "kill -TERM $$"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if status, exited := reapChildren(process.Pid); exited {
+			if code := exitCode(status); code != 128+int(syscall.SIGTERM) {
+				t.Fatalf("expected exit code %d but received %d", 128+int(syscall.SIGTERM), code)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the process to exit")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// give the orphan time to exit in case it is slower than the process
+	for time.Now().Before(deadline) {
+		reapChildren(process.Pid)
+		var status syscall.WaitStatus
+		if _, err := syscall.Wait4(orphan.Pid, &status, syscall.WNOHANG, nil); err == syscall.ECHILD {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected the orphan to be reaped")
+}
@@ -0,0 +1,775 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/cgroups/fs"
+	"github.com/docker/libcontainer/cgroups/systemd"
+	"github.com/docker/libcontainer/utils"
+)
+
+// freezing is the transient state reported by the freezer cgroup while
+// the processes are being frozen.
+const freezing cgroups.FreezerState = "FREEZING"
+
+// linuxContainer implements the libcontainer.Container interface for a container
+// stored in a directory managed by a linuxFactory.
+type linuxContainer struct {
+	id       string
+	root     string
+	initPath string
+	config   *libcontainer.Config
+
+	// m guards the container's state transitions
+	m         sync.Mutex
+	destroyed bool
+}
+
+func newLinuxContainer(id, root, initPath string, config *libcontainer.Config) *linuxContainer {
+	return &linuxContainer{
+		id:       id,
+		root:     root,
+		initPath: initPath,
+		config:   config,
+	}
+}
+
+func (c *linuxContainer) ID() string {
+	return c.id
+}
+
+func (c *linuxContainer) Config() *libcontainer.Config {
+	return c.config
+}
+
+func (c *linuxContainer) RunState() (*libcontainer.RunState, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	state, err := c.runState()
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// info describes the container for Factory.List.
+func (c *linuxContainer) info() (*libcontainer.ContainerInfo, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	runState, err := c.runState()
+	if err != nil {
+		return nil, err
+	}
+
+	created, cerr := readCreated(c.root)
+	if cerr != nil {
+		return nil, libcontainer.NewSystemError(cerr)
+	}
+
+	info := &libcontainer.ContainerInfo{
+		ID:       c.id,
+		State:    runState,
+		Created:  created,
+		RootFs:   c.config.RootFs,
+		Hostname: c.config.Hostname,
+		Labels:   c.config.Labels,
+	}
+	if runState != libcontainer.Stopped {
+		state, err := libcontainer.GetState(c.root)
+		if err != nil {
+			return nil, libcontainer.NewSystemError(err)
+		}
+		info.InitPid = state.InitPid
+		return info, nil
+	}
+
+	status, serr := libcontainer.GetExitStatus(c.root)
+	if serr != nil && !os.IsNotExist(serr) {
+		return nil, libcontainer.NewSystemError(serr)
+	}
+	info.ExitStatus = status
+	return info, nil
+}
+
+func (c *linuxContainer) ExitStatus() (*libcontainer.ExitStatus, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	runState, err := c.runState()
+	if err != nil {
+		return nil, err
+	}
+	if runState != libcontainer.Stopped {
+		return nil, nil
+	}
+
+	status, serr := libcontainer.GetExitStatus(c.root)
+	if serr != nil {
+		if os.IsNotExist(serr) {
+			return nil, nil
+		}
+		return nil, libcontainer.NewSystemError(serr)
+	}
+	return status, nil
+}
+
+// Start starts the container's init process if it is not running, otherwise the
+// process is started inside the namespaces and cgroups of the running container.
+// The Env, User, Cwd and Tty of the ProcessConfig override the container's config
+// only for the started process.
+func (c *linuxContainer) Start(config *libcontainer.ProcessConfig) (libcontainer.Process, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if config == nil {
+		return nil, libcontainer.NewGenericError(fmt.Errorf("process config for container %q is nil", c.id), libcontainer.ConfigInvalid)
+	}
+
+	l, err := c.lock(true)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	runState, err := c.runState()
+	if err != nil {
+		return nil, err
+	}
+
+	switch runState {
+	case libcontainer.Pausing, libcontainer.Paused:
+		return nil, libcontainer.NewGenericError(fmt.Errorf("container %q is paused", c.id), libcontainer.ContainerPaused)
+	case libcontainer.Stopped:
+		// the state of an init process that died without cleaning up is removed
+		// before a new init is started
+		if state, err := libcontainer.GetState(c.root); err == nil {
+			if err := c.cleanup(state, false); err != nil {
+				return nil, libcontainer.NewSystemError(err)
+			}
+		}
+		container, release, err := c.resolveNamespaces(c.processConfig(config))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		if config.Detach {
+			return c.startDetached(config, container)
+		}
+		return c.startInit(config, container)
+	}
+	if config.Detach {
+		return nil, libcontainer.NewGenericError(fmt.Errorf("only the init process of container %q can be detached", c.id), libcontainer.ConfigInvalid)
+	}
+	return c.startInContainer(config)
+}
+
+// startInit starts the init process of the container via Exec.  The container's
+// directory is used as the data path so the exit status of the init process is
+// recorded in it.
+func (c *linuxContainer) startInit(config *libcontainer.ProcessConfig, container *libcontainer.Config) (libcontainer.Process, libcontainer.Error) {
+	process, err := newLinuxProcess(config)
+	if err != nil {
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	createCommand := func(container *libcontainer.Config, console, dataPath, init string, pipe *os.File, args []string) *exec.Cmd {
+		process.cmd = DefaultCreateCommand(container, console, dataPath, c.initPath, pipe, args)
+		return process.cmd
+	}
+
+	start := func(started func()) error {
+		_, err := Exec(container, process.childStdin, process.childStdout, process.childStderr, process.console, c.root, config.Args, createCommand, started)
+		return err
+	}
+
+	exitStatus := func() *libcontainer.ExitStatus {
+		if status, err := libcontainer.GetExitStatus(c.root); err == nil {
+			return status
+		}
+		return nil
+	}
+
+	return c.run(config, process, start, exitStatus)
+}
+
+// startInContainer starts a new process inside the running container via ExecIn.
+func (c *linuxContainer) startInContainer(config *libcontainer.ProcessConfig) (libcontainer.Process, libcontainer.Error) {
+	state, err := libcontainer.GetState(c.root)
+	if err != nil {
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	process, err := newLinuxProcess(config)
+	if err != nil {
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	var startTime time.Time
+	start := func(started func()) error {
+		_, err := ExecIn(c.processConfig(config), state, config.Args, c.initPath, "exec", process.childStdin, process.childStdout, process.childStderr, process.console, func(cmd *exec.Cmd) {
+			process.cmd = cmd
+			startTime = time.Now()
+			started()
+		})
+		return err
+	}
+
+	exitStatus := func() *libcontainer.ExitStatus {
+		return newExitStatus(process.cmd.ProcessState, startTime, false)
+	}
+
+	return c.run(config, process, start, exitStatus)
+}
+
+// processConfig returns a copy of the container's config with the overrides of
+// the process applied.
+func (c *linuxContainer) processConfig(process *libcontainer.ProcessConfig) *libcontainer.Config {
+	config := *c.config
+	if process.Env != nil {
+		config.Env = process.Env
+	}
+	if process.User != "" {
+		config.User = process.User
+	}
+	if process.Cwd != "" {
+		config.WorkingDir = process.Cwd
+	}
+	config.Tty = process.Tty
+	return &config
+}
+
+// run calls start in a new goroutine and waits until either the process is started
+// or start returns an error.  Once the process exits its stdio is closed and the
+// status returned by exitStatus is reported by the process' Wait method.
+func (c *linuxContainer) run(config *libcontainer.ProcessConfig, process *linuxProcess, start func(started func()) error, exitStatus func() *libcontainer.ExitStatus) (libcontainer.Process, libcontainer.Error) {
+	var (
+		startedc = make(chan struct{}, 1)
+		errc     = make(chan error, 1)
+	)
+
+	go func() {
+		err := start(func() {
+			process.closeChildFiles()
+			startedc <- struct{}{}
+		})
+		closeStdio(config)
+		if err != nil {
+			errc <- err
+			process.err = libcontainer.NewSystemError(err)
+		} else if process.status = exitStatus(); process.status == nil {
+			process.err = libcontainer.NewGenericError(fmt.Errorf("unable to get the exit status of process %d", process.Pid()), libcontainer.SystemError)
+		}
+		close(process.done)
+	}()
+
+	select {
+	case <-startedc:
+		return process, nil
+	case err := <-errc:
+		process.closePipes()
+		return nil, libcontainer.NewSystemError(err)
+	}
+}
+
+func (c *linuxContainer) Set(cgroup *cgroups.Cgroup) libcontainer.Error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	if c.config.Cgroups == nil {
+		return libcontainer.NewGenericError(fmt.Errorf("container %q has no cgroups configured", c.id), libcontainer.ConfigInvalid)
+	}
+	if cgroup == nil {
+		return libcontainer.NewGenericError(fmt.Errorf("cgroup for container %q is nil", c.id), libcontainer.ConfigInvalid)
+	}
+
+	old := c.config.Cgroups
+	updated := *cgroup
+	if updated.Name == "" && updated.Parent == "" && updated.Slice == "" {
+		updated.Name, updated.Parent, updated.Slice = old.Name, old.Parent, old.Slice
+	}
+	if updated.Name != old.Name || updated.Parent != old.Parent || updated.Slice != old.Slice {
+		return libcontainer.NewGenericError(fmt.Errorf("the cgroup of container %q cannot be moved", c.id), libcontainer.ConfigInvalid)
+	}
+	// the freezer state is changed by Pause and Resume and is never saved in the config
+	updated.Freezer = cgroups.Undefined
+
+	config := *c.config
+	config.Cgroups = &updated
+	if err := config.Validate(); err != nil {
+		return libcontainer.NewGenericError(err, libcontainer.ConfigInvalid)
+	}
+
+	runState, err := c.runState()
+	if err != nil {
+		return err
+	}
+	if runState != libcontainer.Stopped {
+		state, serr := libcontainer.GetState(c.root)
+		if serr != nil {
+			return libcontainer.NewSystemError(serr)
+		}
+		if err := UpdateCgroups(c.config, &updated, state); err != nil {
+			return libcontainer.NewSystemError(err)
+		}
+	}
+
+	if err := utils.WriteJSON(filepath.Join(c.root, configFilename), &config); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	c.config = &config
+
+	return nil
+}
+
+func (c *linuxContainer) Signal(sig os.Signal) libcontainer.Error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(false)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	state, err := c.currentState()
+	if err != nil {
+		return err
+	}
+
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return libcontainer.NewGenericError(fmt.Errorf("unsupported signal %s", sig), libcontainer.SystemError)
+	}
+	if err := syscall.Kill(state.InitPid, s); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	return nil
+}
+
+// Stop sends the stop signal to the init process and waits for it to exit for at
+// most timeout.  The remaining processes of the container are then killed and
+// the container's cgroups are removed.
+func (c *linuxContainer) Stop(timeout time.Duration) libcontainer.Error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	runState, err := c.runState()
+	if err != nil {
+		return err
+	}
+	if runState == libcontainer.Stopped {
+		return nil
+	}
+
+	state, serr := libcontainer.GetState(c.root)
+	if serr != nil {
+		return libcontainer.NewSystemError(serr)
+	}
+
+	// the processes of a paused container cannot handle the stop signal
+	if runState == libcontainer.Paused || runState == libcontainer.Pausing {
+		if err := c.freeze(cgroups.Thawed); err != nil {
+			return libcontainer.NewSystemError(err)
+		}
+	}
+
+	sig := syscall.SIGTERM
+	if c.config.StopSignal != 0 {
+		sig = syscall.Signal(c.config.StopSignal)
+	}
+	if err := syscall.Kill(state.InitPid, sig); err != nil && err != syscall.ESRCH {
+		return libcontainer.NewSystemError(err)
+	}
+	waitInitExit(state, timeout)
+
+	// the pid of an init that exited may have been reused, without cgroups
+	// there is nothing left to kill
+	if c.config.Cgroups != nil || !initExited(state) {
+		if err := c.killAll(state); err != nil {
+			return libcontainer.NewSystemError(err)
+		}
+		if !waitInitExit(state, stopKillTimeout) {
+			return libcontainer.NewGenericError(fmt.Errorf("init process %d of container %q did not exit after SIGKILL", state.InitPid, c.id), libcontainer.SystemError)
+		}
+	}
+
+	if err := cgroups.RemovePaths(state.CgroupPaths); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	return nil
+}
+
+// Destroy kills all of the container's processes and removes the container's
+// cgroups, network interfaces and directory.
+func (c *linuxContainer) Destroy() libcontainer.Error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.destroyed {
+		return nil
+	}
+
+	l, lerr := c.lock(true)
+	if lerr != nil {
+		if lerr.Code() == libcontainer.ContainerDestroyed {
+			return nil
+		}
+		return lerr
+	}
+	defer l.Unlock()
+
+	state, err := libcontainer.GetState(c.root)
+	if err != nil && !os.IsNotExist(err) {
+		return libcontainer.NewSystemError(err)
+	}
+
+	if err := c.errInUse(); err != nil {
+		return err
+	}
+
+	if state != nil {
+		if err := c.cleanup(state, initAlive(state)); err != nil {
+			return libcontainer.NewSystemError(err)
+		}
+	}
+
+	if err := unmountNamespaces(c.root); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	if err := os.RemoveAll(c.root); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	c.destroyed = true
+
+	return nil
+}
+
+// killAll sends SIGKILL to the init process and every process in the container's
+// cgroup.  Without a pid namespace the processes could fork while they are being
+// killed so the cgroup is frozen first.  A frozen container is thawed so that
+// the signals are delivered.
+func (c *linuxContainer) killAll(state *libcontainer.State) error {
+	// the cgroups of a stopped container are removed while its state may remain
+	if !c.config.Namespaces.Contains(libcontainer.NEWPID) && cgroupExists(state, "freezer") {
+		if err := c.freeze(cgroups.Frozen); err != nil {
+			return err
+		}
+	}
+
+	pids, err := c.getPids(state)
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+
+	if freezerState(state) != cgroups.Thawed {
+		return c.freeze(cgroups.Thawed)
+	}
+	return nil
+}
+
+func (c *linuxContainer) Processes() ([]int, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	state, err := c.currentState()
+	if err != nil {
+		return nil, err
+	}
+
+	pids, perr := c.getPids(state)
+	if perr != nil {
+		return nil, libcontainer.NewSystemError(perr)
+	}
+	return pids, nil
+}
+
+// getPids returns the pids in the container's cgroup, or only the init process'
+// pid when the container has no cgroups configured.
+func (c *linuxContainer) getPids(state *libcontainer.State) ([]int, error) {
+	// rootless containers run without cgroups if the user cannot write to them,
+	// the pid of a dead init may have been reused by an unrelated process
+	if c.config.Cgroups == nil || len(state.CgroupPaths) == 0 {
+		if !initAlive(state) {
+			return nil, nil
+		}
+		return []int{state.InitPid}, nil
+	}
+
+	var (
+		pids []int
+		err  error
+	)
+	if useSystemd(c.config) {
+		pids, err = systemd.GetPids(c.config.Cgroups)
+	} else {
+		pids, err = fs.GetPids(c.config.Cgroups)
+	}
+	// cgroups removed by Stop hold no processes
+	if err != nil && (os.IsNotExist(err) || cgroups.IsNotFound(err)) {
+		return nil, nil
+	}
+	return pids, err
+}
+
+// cgroupExists reports whether the cgroup of the subsystem recorded in state exists.
+func cgroupExists(state *libcontainer.State, subsystem string) bool {
+	path, ok := state.CgroupPaths[subsystem]
+	if !ok {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (c *linuxContainer) Stats() (*libcontainer.ContainerStats, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	state, err := c.currentState()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, serr := libcontainer.GetStats(c.config, state)
+	if serr != nil {
+		return nil, libcontainer.NewSystemError(serr)
+	}
+	return stats, nil
+}
+
+func (c *linuxContainer) Pause() libcontainer.Error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	runState, err := c.runState()
+	if err != nil {
+		return err
+	}
+
+	switch runState {
+	case libcontainer.Paused:
+		return nil
+	case libcontainer.Stopped:
+		return libcontainer.NewGenericError(fmt.Errorf("container %q is not running", c.id), libcontainer.ContainerNotRunning)
+	}
+
+	if err := c.freeze(cgroups.Frozen); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	return nil
+}
+
+func (c *linuxContainer) Resume() libcontainer.Error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	runState, err := c.runState()
+	if err != nil {
+		return err
+	}
+
+	switch runState {
+	case libcontainer.Running:
+		return nil
+	case libcontainer.Stopped:
+		return libcontainer.NewGenericError(fmt.Errorf("container %q is not running", c.id), libcontainer.ContainerNotRunning)
+	}
+
+	if err := c.freeze(cgroups.Thawed); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	return nil
+}
+
+func (c *linuxContainer) freeze(state cgroups.FreezerState) error {
+	if c.config.Cgroups == nil {
+		return fmt.Errorf("container %q has no cgroups configured", c.id)
+	}
+
+	// Freeze records the state in the cgroup it is given so a copy is frozen, the
+	// state would be applied by the next Start otherwise
+	cg := *c.config.Cgroups
+	if useSystemd(c.config) {
+		return systemd.Freeze(&cg, state)
+	}
+	return fs.Freeze(&cg, state)
+}
+
+// lock takes the lock on the container's directory so that operations on the
+// container are serialized with other processes.  The exclusive lock is taken
+// by operations that change the state of the container.
+func (c *linuxContainer) lock(exclusive bool) (*FileLock, libcontainer.Error) {
+	lock := RLock
+	if exclusive {
+		lock = Lock
+	}
+
+	l, err := lock(c.root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.destroyed = true
+			return nil, c.errDestroyed()
+		}
+		return nil, libcontainer.NewSystemError(err)
+	}
+	return l, nil
+}
+
+// runState returns the state of the container based on the contents of its
+// directory, the start time of its init process and the freezer cgroup.
+func (c *linuxContainer) runState() (libcontainer.RunState, libcontainer.Error) {
+	if c.destroyed {
+		return libcontainer.Destroyed, c.errDestroyed()
+	}
+
+	if _, err := os.Stat(c.root); err != nil {
+		if os.IsNotExist(err) {
+			c.destroyed = true
+			return libcontainer.Destroyed, c.errDestroyed()
+		}
+		return libcontainer.Destroyed, libcontainer.NewSystemError(err)
+	}
+
+	state, err := libcontainer.GetState(c.root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return libcontainer.Stopped, nil
+		}
+		return libcontainer.Destroyed, libcontainer.NewSystemError(err)
+	}
+	// a state left behind by a dead init process is stale
+	if !initAlive(state) {
+		return libcontainer.Stopped, nil
+	}
+
+	switch freezerState(state) {
+	case freezing:
+		return libcontainer.Pausing, nil
+	case cgroups.Frozen:
+		return libcontainer.Paused, nil
+	}
+	return libcontainer.Running, nil
+}
+
+// currentState returns the runtime state of the running container.
+func (c *linuxContainer) currentState() (*libcontainer.State, libcontainer.Error) {
+	runState, err := c.runState()
+	if err != nil {
+		return nil, err
+	}
+	if runState == libcontainer.Stopped {
+		return nil, libcontainer.NewGenericError(fmt.Errorf("container %q is not running", c.id), libcontainer.ContainerNotRunning)
+	}
+
+	state, serr := libcontainer.GetState(c.root)
+	if serr != nil {
+		return nil, libcontainer.NewSystemError(serr)
+	}
+	return state, nil
+}
+
+func (c *linuxContainer) errDestroyed() libcontainer.Error {
+	return libcontainer.NewGenericError(fmt.Errorf("container %q is destroyed", c.id), libcontainer.ContainerDestroyed)
+}
+
+// freezerState reads the current state of the container's freezer cgroup.  Thawed
+// is returned when the container is not in a freezer cgroup.
+func freezerState(state *libcontainer.State) cgroups.FreezerState {
+	dir, ok := state.CgroupPaths["freezer"]
+	if !ok {
+		return cgroups.Thawed
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "freezer.state"))
+	if err != nil {
+		return cgroups.Thawed
+	}
+	return cgroups.FreezerState(strings.TrimSpace(string(data)))
+}
+
+// closeStdio closes the process' stdio once it has terminated.
+func closeStdio(process *libcontainer.ProcessConfig) {
+	if process.Stdin != nil {
+		process.Stdin.Close()
+	}
+	if process.Stdout != nil {
+		process.Stdout.Close()
+	}
+	if process.Stderr != nil {
+		process.Stderr.Close()
+	}
+}
@@ -0,0 +1,425 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/system"
+)
+
+func TestContainerRunStateStopped(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := container.RunState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *state != libcontainer.Stopped {
+		t.Fatalf("expected container to be stopped but received state %s", *state)
+	}
+
+	if err := container.Pause(); err == nil {
+		t.Fatal("expected error pausing a stopped container")
+	}
+	if _, err := container.Stats(); err == nil {
+		t.Fatal("expected error getting the stats of a stopped container")
+	}
+}
+
+func TestContainerDestroy(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := container.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "test")); !os.IsNotExist(err) {
+		t.Fatal("expected container directory to be removed")
+	}
+
+	_, err = container.RunState()
+	if err == nil {
+		t.Fatal("expected error getting the state of a destroyed container")
+	}
+	if err.Code() != libcontainer.ContainerDestroyed {
+		t.Fatalf("expected ContainerDestroyed but received %d", err.Code())
+	}
+
+	if err := container.Destroy(); err != nil {
+		t.Fatalf("expected no error destroying a destroyed container but received %s", err)
+	}
+}
+
+func TestContainerStartNilProcess(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = container.Start(nil)
+	if err == nil {
+		t.Fatal("expected error starting a nil process")
+	}
+	if err.Code() != libcontainer.ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid but received %d", err.Code())
+	}
+}
+
+func TestContainerExitStatus(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := container.ExitStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != nil {
+		t.Fatalf("expected no exit status for a container that was never started but received %+v", status)
+	}
+
+	cmd := exec.Command("sh", "-c", "kill -9 $$")
+	started := time.Now()
+	if err := cmd.Run(); err == nil {
+		t.Fatal("expected the process to be killed")
+	}
+	if err := libcontainer.SaveExitStatus(filepath.Join(root, "test"), newExitStatus(cmd.ProcessState, started, false)); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, lerr := factory.Load("test")
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	status, err = loaded.ExitStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil {
+		t.Fatal("expected the exit status of the stopped container")
+	}
+	if status.ExitCode != -1 || status.Signal != int(syscall.SIGKILL) {
+		t.Fatalf("expected the process to be killed by SIGKILL but received exit code %d and signal %d", status.ExitCode, status.Signal)
+	}
+	if status.Rusage == nil {
+		t.Fatal("expected the resource usage of the process")
+	}
+	if status.Finished.Before(status.Started) {
+		t.Fatalf("expected finish time %s to be after start time %s", status.Finished, status.Started)
+	}
+}
+
+func TestContainerProcessConfigOverrides(t *testing.T) {
+	c := &linuxContainer{
+		id: "test",
+		config: &libcontainer.Config{
+			Env:        []string{"PATH=/bin"},
+			User:       "root",
+			WorkingDir: "/",
+		},
+	}
+
+	config := c.processConfig(&libcontainer.ProcessConfig{})
+	if config.User != "root" || config.WorkingDir != "/" || len(config.Env) != 1 {
+		t.Fatalf("expected the container's config to be used without overrides but received %+v", config)
+	}
+
+	config = c.processConfig(&libcontainer.ProcessConfig{
+		Env:  []string{"PATH=/usr/bin", "TERM=xterm"},
+		User: "daemon",
+		Cwd:  "/tmp",
+		Tty:  true,
+	})
+	if config.User != "daemon" || config.WorkingDir != "/tmp" || len(config.Env) != 2 || !config.Tty {
+		t.Fatalf("expected the process' overrides to be applied but received %+v", config)
+	}
+	if c.config.User != "root" || c.config.Tty {
+		t.Fatal("expected the container's config to be unchanged")
+	}
+}
+
+func TestContainerStartDetachedRequiresFiles(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, w := io.Pipe()
+	defer r.Close()
+
+	_, err = container.Start(&libcontainer.ProcessConfig{
+		Args:   []string{"true"},
+		Detach: true,
+		Stdout: w,
+	})
+	if err == nil {
+		t.Fatal("expected error detaching a process with a non file stdout")
+	}
+	if err.Code() != libcontainer.ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid but received %d", err.Code())
+	}
+}
+
+func TestContainerSignalStopped(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = container.Signal(syscall.SIGTERM)
+	if err == nil {
+		t.Fatal("expected error signaling a stopped container")
+	}
+	if err.Code() != libcontainer.ContainerNotRunning {
+		t.Fatalf("expected ContainerNotRunning but received %d", err.Code())
+	}
+}
+
+func TestContainerSetStopped(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	container, err := factory.Create("test", &libcontainer.Config{
+		Cgroups: &cgroups.Cgroup{Name: "test", Parent: "libcontainer", Memory: 1024},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = container.Set(&cgroups.Cgroup{Memory: -1})
+	if err == nil || err.Code() != libcontainer.ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid for a negative memory limit but received %v", err)
+	}
+	err = container.Set(&cgroups.Cgroup{Name: "other", Parent: "libcontainer"})
+	if err == nil || err.Code() != libcontainer.ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid for moving the cgroup but received %v", err)
+	}
+
+	if err := container.Set(&cgroups.Cgroup{Memory: 4096, CpuShares: 512, Freezer: cgroups.Frozen}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := factory.Load("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := loaded.Config().Cgroups
+	if c.Name != "test" || c.Memory != 4096 || c.CpuShares != 512 || c.Freezer != cgroups.Undefined {
+		t.Fatalf("expected the updated cgroup to be persisted but received %+v", c)
+	}
+}
+
+func TestUpdateCgroupsExitedInit(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &libcontainer.Config{Cgroups: &cgroups.Cgroup{Name: "test", Memory: 1024}}
+	err := UpdateCgroups(config, &cgroups.Cgroup{Name: "test", Memory: 4096}, &libcontainer.State{InitPid: cmd.Process.Pid, InitStartTime: "1"})
+	if lerr, ok := err.(libcontainer.Error); !ok || lerr.Code() != libcontainer.ContainerNotRunning {
+		t.Fatalf("expected ContainerNotRunning for an exited init but received %v", err)
+	}
+}
+
+func TestContainerStop(t *testing.T) {
+	for _, test := range []struct {
+		script   string
+		config   *libcontainer.Config
+		expected syscall.Signal
+	}{
+		// the init exits on the stop signal
+		{"sleep 100", &libcontainer.Config{}, syscall.SIGTERM},
+		{"sleep 100", &libcontainer.Config{StopSignal: int(syscall.SIGUSR1)}, syscall.SIGUSR1},
+		// the init ignores the stop signal and is killed after the timeout
+		{"trap '' TERM; while :; do sleep 0.1; done", &libcontainer.Config{}, syscall.SIGKILL},
+	} {
+		factory, root := newTestFactory(t)
+		defer os.RemoveAll(root)
+
+		container, err := factory.Create("test", test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command("sh", "-c", test.script)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		started, serr := system.GetProcessStartTime(cmd.Process.Pid)
+		if serr != nil {
+			t.Fatal(serr)
+		}
+		if err := libcontainer.SaveState(filepath.Join(root, "test"), &libcontainer.State{InitPid: cmd.Process.Pid, InitStartTime: started}); err != nil {
+			t.Fatal(err)
+		}
+		// give the shell time to install its trap
+		time.Sleep(200 * time.Millisecond)
+
+		if err := container.Stop(500 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd.Wait()
+		ws := cmd.ProcessState.Sys().(syscall.WaitStatus)
+		if !ws.Signaled() || ws.Signal() != test.expected {
+			t.Fatalf("expected %q to be terminated by %s but received %s", test.script, test.expected, cmd.ProcessState)
+		}
+	}
+}
+
+func TestContainerJoinNamespaces(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	netns := &libcontainer.Config{Namespaces: libcontainer.Namespaces{{Type: libcontainer.NEWNET}}}
+	if _, err := factory.Create("netns", netns); err != nil {
+		t.Fatal(err)
+	}
+	config := &libcontainer.Config{Namespaces: libcontainer.Namespaces{{Type: libcontainer.NEWNET, Container: "netns"}}}
+	container, err := factory.Create("test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the referenced container has to be running
+	if _, err := container.Start(&libcontainer.ProcessConfig{Args: []string{"true"}}); err == nil || err.Code() != libcontainer.ContainerNotRunning {
+		t.Fatalf("expected ContainerNotRunning but received %v", err)
+	}
+
+	// the namespaces persisted by a stopped container are joined
+	cmd := exec.Command("sleep", "10")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWNET}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	if _, err := persistNamespaces(netns, cmd.Process.Pid, filepath.Join(root, "netns")); err != nil {
+		t.Fatal(err)
+	}
+	defer unmountNamespaces(filepath.Join(root, "netns"))
+
+	resolved, release, err := container.(*linuxContainer).resolveNamespaces(container.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if expected := filepath.Join(root, "netns", nsDirname, "net"); resolved.Namespaces[0].Path != expected {
+		t.Fatalf("expected the namespace to be resolved to %s but received %q", expected, resolved.Namespaces[0].Path)
+	}
+
+	// the namespaces of a running init are preferred
+	started, serr := system.GetProcessStartTime(os.Getpid())
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	if err := libcontainer.SaveState(filepath.Join(root, "netns"), &libcontainer.State{InitPid: os.Getpid(), InitStartTime: started}); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, release, err = container.(*linuxContainer).resolveNamespaces(container.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if expected := fmt.Sprintf("/proc/%d/ns/net", os.Getpid()); resolved.Namespaces[0].Path != expected {
+		t.Fatalf("expected the namespace to be resolved to %s but received %q", expected, resolved.Namespaces[0].Path)
+	}
+	if container.Config().Namespaces[0].Path != "" {
+		t.Fatal("expected the container's config not to be changed")
+	}
+}
+
+func TestContainerDestroyInUse(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	netns, err := factory.Create("netns", &libcontainer.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := factory.Create("test", &libcontainer.Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	started, serr := system.GetProcessStartTime(os.Getpid())
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	if err := libcontainer.SaveState(filepath.Join(root, "test"), &libcontainer.State{
+		InitPid:          os.Getpid(),
+		InitStartTime:    started,
+		JoinedNamespaces: map[libcontainer.NamespaceType]string{libcontainer.NEWNET: "netns"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := netns.Destroy(); err == nil || err.Code() != libcontainer.ContainerInUse {
+		t.Fatalf("expected ContainerInUse but received %v", err)
+	}
+
+	if err := libcontainer.DeleteState(filepath.Join(root, "test")); err != nil {
+		t.Fatal(err)
+	}
+	if err := netns.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOwnsState(t *testing.T) {
+	root, err := ioutil.TempDir("", "libcontainer-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if ownsState(root, 1, "1") {
+		t.Fatal("expected a missing state to be owned by no process")
+	}
+	if err := libcontainer.SaveState(root, &libcontainer.State{InitPid: 1, InitStartTime: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !ownsState(root, 1, "1") {
+		t.Fatal("expected the state to be owned by the init it was written for")
+	}
+	// the state of a new init started after the first one exited
+	if ownsState(root, 1, "2") || ownsState(root, 2, "1") {
+		t.Fatal("expected the state of another init not to be owned")
+	}
+}
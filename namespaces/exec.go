@@ -3,17 +3,19 @@
 package namespaces
 
 import (
-	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"syscall"
+	"time"
 
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/cgroups/fs"
 	"github.com/docker/libcontainer/cgroups/systemd"
 	"github.com/docker/libcontainer/network"
+	"github.com/docker/libcontainer/reexec"
 	"github.com/docker/libcontainer/system"
 )
 
@@ -21,9 +23,24 @@ import (
 // Move this to libcontainer package.
 // Exec performs setup outside of a namespace so that a container can be
 // executed.  Exec is a high level function for working with container namespaces.
+// The container's prestart, poststart and poststop hooks are run by Exec.  Once
+// the init process exits its exit status is recorded in dataPath.
 func Exec(container *libcontainer.Config, stdin io.Reader, stdout, stderr io.Writer, console, dataPath string, args []string, createCommand CreateCommand, startCallback func()) (int, error) {
 	var err error
 
+	// catch inconsistent configs before any process is cloned for the container
+	if err := container.Validate(); err != nil {
+		return -1, libcontainer.NewGenericError(err, libcontainer.ConfigInvalid)
+	}
+	if err := checkPrivileges(container); err != nil {
+		return -1, libcontainer.NewGenericError(err, libcontainer.ConfigInvalid)
+	}
+	for _, ns := range container.Namespaces {
+		if ns.Container != "" && ns.Path == "" {
+			return -1, libcontainer.NewGenericError(fmt.Errorf("namespace %s of container %q is not resolved, it is joined by Container.Start", ns.Type, ns.Container), libcontainer.ConfigInvalid)
+		}
+	}
+
 	// create a pipe so that we can syncronize with the namespaced process and
 	// pass the state and configuration to the child process
 	parent, child, err := newInitPipe()
@@ -32,7 +49,7 @@ func Exec(container *libcontainer.Config, stdin io.Reader, stdout, stderr io.Wri
 	}
 	defer parent.Close()
 
-	command := createCommand(container, console, dataPath, os.Args[0], child, args)
+	command := createCommand(container, console, dataPath, reexec.Self(), child, args)
 	// Note: these are only used in non-tty mode
 	// if there is a tty for the container it will be opened within the namespace and the
 	// fds will be duped to stdin, stdiout, and stderr
@@ -40,16 +57,28 @@ func Exec(container *libcontainer.Config, stdin io.Reader, stdout, stderr io.Wri
 	command.Stdout = stdout
 	command.Stderr = stderr
 
+	// the exit status of a previous run of the container is no longer valid
+	if err := libcontainer.DeleteExitStatus(dataPath); err != nil && !os.IsNotExist(err) {
+		return -1, err
+	}
+
 	if err := command.Start(); err != nil {
 		child.Close()
 		return -1, err
 	}
 	child.Close()
+	startTime := time.Now()
 
+	// persisted is set once the namespace files of the init are bind mounted, the files
+	// of a previous init are kept until then as the new init may be joining them
+	persisted := false
 	terminate := func(terr error) (int, error) {
 		// TODO: log the errors for kill and wait
 		command.Process.Kill()
 		command.Wait()
+		if persisted {
+			unmountNamespaces(dataPath)
+		}
 		return -1, terr
 	}
 
@@ -64,52 +93,112 @@ func Exec(container *libcontainer.Config, stdin io.Reader, stdout, stderr io.Wri
 	if err != nil {
 		return terminate(err)
 	}
-	defer cgroups.RemovePaths(cgroupPaths)
+	// saved is set once the state of the init is written to dataPath
+	saved := false
+	defer func() {
+		// once the init exited the container can be started again before Exec returns,
+		// the state and cgroups of the new init are left alone
+		if saved {
+			if !ownsState(dataPath, command.Process.Pid, started) {
+				return
+			}
+			libcontainer.DeleteState(dataPath)
+		}
+		cgroups.RemovePaths(cgroupPaths)
+	}()
+
+	if err := chownUserNamespace(container, console, cgroupPaths); err != nil {
+		return terminate(err)
+	}
 
 	var networkState network.NetworkState
 	if err := InitializeNetworking(container, command.Process.Pid, &networkState); err != nil {
 		return terminate(err)
 	}
-	// send the state to the container's init process then shutdown writes for the parent
-	if err := json.NewEncoder(parent).Encode(networkState); err != nil {
+
+	state := &libcontainer.State{
+		InitPid:          command.Process.Pid,
+		InitStartTime:    started,
+		NetworkState:     networkState,
+		CgroupPaths:      cgroupPaths,
+		JoinedNamespaces: joinedNamespaces(container),
+	}
+
+	if err := libcontainer.SaveState(dataPath, state); err != nil {
 		return terminate(err)
 	}
-	// shutdown writes for the parent side of the pipe
-	if err := syscall.Shutdown(int(parent.Fd()), syscall.SHUT_WR); err != nil {
+	saved = true
+
+	oomKilled := watchOOM(state)
+
+	// send the config and state to the container's init process and wait for it to
+	// setup the container
+	if err := writeSync(parent, procConfig, initConfig{Config: container, NetworkState: &networkState}); err != nil {
 		return terminate(err)
 	}
+	if err := readSync(parent, procReady, nil); err != nil {
+		return terminate(syncError(err))
+	}
 
-	state := &libcontainer.State{
-		InitPid:       command.Process.Pid,
-		InitStartTime: started,
-		NetworkState:  networkState,
-		CgroupPaths:   cgroupPaths,
+	// the init joined or created all of its namespaces once it is ready
+	if container.PersistNamespaces {
+		persisted = true
+		if state.NamespacePaths, err = persistNamespaces(container, command.Process.Pid, dataPath); err != nil {
+			return terminate(err)
+		}
+		if err := libcontainer.SaveState(dataPath, state); err != nil {
+			return terminate(err)
+		}
 	}
 
-	if err := libcontainer.SaveState(dataPath, state); err != nil {
+	// the init process is blocked on the sync pipe so the user's process is
+	// not executed before the prestart hooks are done
+	if container.Hooks != nil {
+		if err := libcontainer.RunHooks(container.Hooks.Prestart, state); err != nil {
+			return terminate(libcontainer.NewSystemErrorWithCause(err, "prestart"))
+		}
+	}
+	if err := writeSync(parent, procRun, nil); err != nil {
 		return terminate(err)
 	}
-	defer libcontainer.DeleteState(dataPath)
 
-	// wait for the child process to fully complete and receive an error message
-	// if one was encoutered
-	var ierr *initError
-	if err := json.NewDecoder(parent).Decode(&ierr); err != nil && err != io.EOF {
-		return terminate(err)
+	if container.Hooks != nil {
+		// poststop hooks are run once the init process that was allowed to execute
+		// the user's process is gone, failures are ignored because there is nothing
+		// left to cleanup
+		defer func() {
+			for _, hook := range container.Hooks.Poststop {
+				hook.Run(state)
+			}
+		}()
 	}
-	if ierr != nil {
-		return terminate(ierr)
+
+	// wait for the init process to execute the user's process and receive an error
+	// message if one was encoutered
+	if err := waitSyncClose(parent); err != nil {
+		return terminate(syncError(err))
 	}
 
 	if startCallback != nil {
 		startCallback()
 	}
 
+	if container.Hooks != nil {
+		if err := libcontainer.RunHooks(container.Hooks.Poststart, state); err != nil {
+			return terminate(libcontainer.NewSystemErrorWithCause(err, "poststart"))
+		}
+	}
+
 	if err := command.Wait(); err != nil {
 		if _, ok := err.(*exec.ExitError); !ok {
 			return -1, err
 		}
 	}
+	if ownsState(dataPath, command.Process.Pid, started) {
+		if err := libcontainer.SaveExitStatus(dataPath, newExitStatus(command.ProcessState, startTime, oomKilled())); err != nil {
+			return -1, err
+		}
+	}
 	return command.ProcessState.Sys().(syscall.WaitStatus).ExitStatus(), nil
 }
 
@@ -130,7 +219,7 @@ func DefaultCreateCommand(container *libcontainer.Config, console, dataPath, ini
 		"data_path=" + dataPath,
 	}
 
-	command := exec.Command(init, append([]string{"init", "--"}, args...)...)
+	command := reexec.Command(init, "init", append([]string{"--"}, args...)...)
 	// make sure the process is executed inside the context of the rootfs
 	command.Dir = container.RootFs
 	command.Env = append(os.Environ(), env...)
@@ -138,8 +227,12 @@ func DefaultCreateCommand(container *libcontainer.Config, console, dataPath, ini
 	if command.SysProcAttr == nil {
 		command.SysProcAttr = &syscall.SysProcAttr{}
 	}
-	command.SysProcAttr.Cloneflags = uintptr(GetNamespaceFlags(container.Namespaces, true))
+	// the cgroup namespace is unshared by the init once it was moved into the cgroups
+	command.SysProcAttr.Cloneflags = uintptr(GetNamespaceFlags(container.Namespaces, true) &^ system.CLONE_NEWCGROUP)
+	setupUserNamespace(command, container)
 
+	// the init process dies with the process that started it, detached containers
+	// are started by a supervisor process that outlives the caller
 	command.SysProcAttr.Pdeathsig = syscall.SIGKILL
 	command.ExtraFiles = []*os.File{pipe}
 
@@ -147,18 +240,58 @@ func DefaultCreateCommand(container *libcontainer.Config, console, dataPath, ini
 }
 
 // SetupCgroups applies the cgroup restrictions to the process running in the container based
-// on the container's configuration
+// on the container's configuration.  The cgroups of a rootless container are skipped when the
+// user cannot write to the cgroup tree.
 func SetupCgroups(container *libcontainer.Config, nspid int) (map[string]string, error) {
 	if container.Cgroups != nil {
 		c := container.Cgroups
-		if systemd.UseSystemd() {
+		if useSystemd(container) {
 			return systemd.Apply(c, nspid)
 		}
-		return fs.Apply(c, nspid)
+		paths, err := fs.Apply(c, nspid)
+		if err != nil && container.Rootless && isPermissionError(err) {
+			return map[string]string{}, nil
+		}
+		return paths, err
 	}
 	return map[string]string{}, nil
 }
 
+// useSystemd reports whether the container's cgroups are managed by systemd.  Rootless
+// containers cannot create units with the system's manager and use the fs implementation.
+func useSystemd(container *libcontainer.Config) bool {
+	return !container.Rootless && systemd.UseSystemd()
+}
+
+// isPermissionError reports whether err is caused by missing privileges or a read only
+// filesystem.
+func isPermissionError(err error) bool {
+	if perr, ok := err.(*os.PathError); ok {
+		err = perr.Err
+	}
+	switch err {
+	case syscall.EACCES, syscall.EPERM, syscall.EROFS:
+		return true
+	}
+	return false
+}
+
+// UpdateCgroups changes the cgroup limits of a running container to the values in c.
+// Only the values that differ from the container's configuration are applied.  A
+// ContainerNotRunning error is returned if the init process recorded in state exited.
+func UpdateCgroups(container *libcontainer.Config, c *cgroups.Cgroup, state *libcontainer.State) error {
+	if container.Cgroups == nil {
+		return fmt.Errorf("container has no cgroups configured")
+	}
+	if !initAlive(state) {
+		return libcontainer.NewGenericError(fmt.Errorf("init process %d of the container is not running", state.InitPid), libcontainer.ContainerNotRunning)
+	}
+	if useSystemd(container) {
+		return systemd.Update(container.Cgroups, c, state.CgroupPaths)
+	}
+	return fs.Update(container.Cgroups, c, state.CgroupPaths)
+}
+
 // InitializeNetworking creates the container's network stack outside of the namespace and moves
 // interfaces into the container's net namespaces if necessary
 func InitializeNetworking(container *libcontainer.Config, nspid int, networkState *network.NetworkState) error {
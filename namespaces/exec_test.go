@@ -0,0 +1,84 @@
+// +build linux
+
+package namespaces
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+// TestHelperInit stands in for the container's init process of the Exec tests.  It
+// follows the sync protocol without setting up the container.
+func TestHelperInit(t *testing.T) {
+	if os.Getenv("LIBCONTAINER_TEST_INIT") != "1" {
+		return
+	}
+	pipe := os.NewFile(3, "pipe")
+	var iconfig *initConfig
+	if err := readSync(pipe, procConfig, &iconfig); err != nil {
+		os.Exit(1)
+	}
+	if err := writeSync(pipe, procReady, nil); err != nil {
+		os.Exit(1)
+	}
+	if err := readSync(pipe, procRun, nil); err != nil {
+		os.Exit(1)
+	}
+	pipe.Close()
+	os.Exit(0)
+}
+
+func helperInitCommand(container *libcontainer.Config, console, dataPath, init string, pipe *os.File, args []string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperInit")
+	cmd.Env = append(os.Environ(), "LIBCONTAINER_TEST_INIT=1")
+	cmd.ExtraFiles = []*os.File{pipe}
+	return cmd
+}
+
+func TestExecPrestartFailure(t *testing.T) {
+	root, err := ioutil.TempDir("", "libcontainer-exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	touch := func(name string) *libcontainer.Hook {
+		return &libcontainer.Hook{Path: "/bin/sh", Args: []string{"-c", "touch " + filepath.Join(root, name)}}
+	}
+	container := &libcontainer.Config{
+		RootFs: root,
+		Hooks: &libcontainer.Hooks{
+			Prestart: []*libcontainer.Hook{
+				{Path: "/bin/sh", Args: []string{"-c", "exit 1"}},
+				touch("prestart"),
+			},
+			Poststart: []*libcontainer.Hook{touch("poststart")},
+			Poststop:  []*libcontainer.Hook{touch("poststop")},
+		},
+	}
+
+	if _, err := Exec(container, nil, nil, nil, "", root, nil, helperInitCommand, nil); err == nil {
+		t.Fatal("expected an error for a failing prestart hook")
+	}
+	for _, name := range []string{"prestart", "poststart", "poststop"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected the %s hook not to run after the prestart hook failed", name)
+		}
+	}
+
+	// the hooks are run once the prestart hooks succeed
+	container.Hooks.Prestart = container.Hooks.Prestart[1:]
+	if _, err := Exec(container, nil, nil, nil, "", root, nil, helperInitCommand, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"prestart", "poststart", "poststop"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Fatalf("expected the %s hook to run: %s", name, err)
+		}
+	}
+}
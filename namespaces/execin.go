@@ -3,12 +3,10 @@
 package namespaces
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
 	"syscall"
 
@@ -16,30 +14,29 @@ import (
 	"github.com/docker/libcontainer/apparmor"
 	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/label"
+	"github.com/docker/libcontainer/reexec"
 	"github.com/docker/libcontainer/system"
 )
 
-// ExecIn reexec's the initPath with the argv 0 rewrite to "nsenter" so that it is able to run the
-// setns code in a single threaded environment joining the existing containers' namespaces.
+// ExecIn reexec's the initPath with the argv 0 rewrite to "nsenter-<action>" so that it is able to run the
+// setns code in a single threaded environment joining the existing containers' namespaces.  The
+// initializer registered under that name with the reexec package is run afterwards.
 func ExecIn(container *libcontainer.Config, state *libcontainer.State, userArgs []string, initPath, action string,
 	stdin io.Reader, stdout, stderr io.Writer, console string, startCallback func(*exec.Cmd)) (int, error) {
 
-	args := []string{fmt.Sprintf("nsenter-%s", action), "--nspid", strconv.Itoa(state.InitPid)}
+	// the pipe is passed as fd 3, nsenter closes it in the process waiting for the
+	// one that joined the namespaces
+	args := []string{"--nspid", strconv.Itoa(state.InitPid), "--pipe", "3"}
+	// the persisted namespace files stay valid if the init's pid is reused
+	if len(state.NamespacePaths) > 0 {
+		args = append(args, "--nsdir", nsDir(state))
+	}
 
 	if console != "" {
 		args = append(args, "--console", console)
 	}
 
-	cmd := &exec.Cmd{
-		Path: initPath,
-		Args: append(args, append([]string{"--"}, userArgs...)...),
-	}
-
-	if filepath.Base(initPath) == initPath {
-		if lp, err := exec.LookPath(initPath); err == nil {
-			cmd.Path = lp
-		}
-	}
+	cmd := reexec.Command(initPath, fmt.Sprintf("nsenter-%s", action), append(args, append([]string{"--"}, userArgs...)...)...)
 
 	parent, child, err := newInitPipe()
 	if err != nil {
@@ -73,9 +70,14 @@ func ExecIn(container *libcontainer.Config, state *libcontainer.State, userArgs
 		return terminate(err)
 	}
 
-	if err := json.NewEncoder(parent).Encode(container); err != nil {
+	if err := writeSync(parent, procConfig, initConfig{Config: container}); err != nil {
 		return terminate(err)
 	}
+	// wait for the process to execute the user's process and receive an error
+	// message if one was encoutered
+	if err := waitSyncClose(parent); err != nil {
+		return terminate(syncError(err))
+	}
 
 	if startCallback != nil {
 		startCallback(cmd)
@@ -89,16 +91,36 @@ func ExecIn(container *libcontainer.Config, state *libcontainer.State, userArgs
 	return cmd.ProcessState.Sys().(syscall.WaitStatus).ExitStatus(), nil
 }
 
+// ReadSetnsConfig reads the config sent by ExecIn over the pipe.  The pipe is left
+// open so that the failures of FinalizeSetns are sent back over it.
+func ReadSetnsConfig(pipe *os.File) (*libcontainer.Config, error) {
+	var iconfig *initConfig
+	if err := readSync(pipe, procConfig, &iconfig); err != nil {
+		return nil, err
+	}
+	if iconfig.Config == nil {
+		return nil, fmt.Errorf("no config for the process joining the container")
+	}
+	return iconfig.Config, nil
+}
+
 // Finalize expects that the setns calls have been setup and that is has joined an
-// existing namespace
-func FinalizeSetns(container *libcontainer.Config, args []string) error {
+// existing namespace.  A failure is sent back to ExecIn over the pipe, which is
+// closed when the process is executed.
+func FinalizeSetns(container *libcontainer.Config, pipe *os.File, args []string) (err error) {
+	defer func() {
+		if err != nil {
+			ReportSetnsError(pipe, err)
+		}
+	}()
+
 	// clear the current processes env and replace it with the environment defined on the container
 	if err := LoadContainerEnvironment(container); err != nil {
 		return err
 	}
 
 	if err := setupRlimits(container); err != nil {
-		return fmt.Errorf("setup rlimits %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setup rlimits")
 	}
 
 	if err := FinalizeNamespace(container); err != nil {
@@ -106,7 +128,7 @@ func FinalizeSetns(container *libcontainer.Config, args []string) error {
 	}
 
 	if err := apparmor.ApplyProfile(container.AppArmorProfile); err != nil {
-		return fmt.Errorf("set apparmor profile %s: %s", container.AppArmorProfile, err)
+		return libcontainer.NewSystemErrorWithCause(err, fmt.Sprintf("set apparmor profile %s", container.AppArmorProfile))
 	}
 
 	if container.ProcessLabel != "" {
@@ -122,6 +144,13 @@ func FinalizeSetns(container *libcontainer.Config, args []string) error {
 	panic("unreachable")
 }
 
+// ReportSetnsError sends err back to ExecIn over the pipe of a process that joined
+// a container and closes the pipe.
+func ReportSetnsError(pipe *os.File, err error) error {
+	defer pipe.Close()
+	return writeSync(pipe, procError, newInitError("setns", err))
+}
+
 func EnterCgroups(state *libcontainer.State, pid int) error {
 	return cgroups.EnterPid(state.CgroupPaths, pid)
 }
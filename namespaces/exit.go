@@ -0,0 +1,93 @@
+// +build linux
+
+package namespaces
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/docker/libcontainer"
+)
+
+// newExitStatus returns the exit status of an init process that was collected
+// with wait4.
+func newExitStatus(ps *os.ProcessState, started time.Time, oomKilled bool) *libcontainer.ExitStatus {
+	status := &libcontainer.ExitStatus{
+		ExitCode:  -1,
+		OOMKilled: oomKilled,
+		Started:   started,
+		Finished:  time.Now(),
+	}
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok {
+		status.ExitCode = ws.ExitStatus()
+		if ws.Signaled() {
+			status.Signal = int(ws.Signal())
+			status.CoreDumped = ws.CoreDump()
+		}
+	}
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok && ru != nil {
+		status.Rusage = &libcontainer.Rusage{
+			UserTime:               time.Duration(syscall.TimevalToNsec(ru.Utime)),
+			SystemTime:             time.Duration(syscall.TimevalToNsec(ru.Stime)),
+			MaxRss:                 int64(ru.Maxrss),
+			MinorFaults:            int64(ru.Minflt),
+			MajorFaults:            int64(ru.Majflt),
+			InBlock:                int64(ru.Inblock),
+			OutBlock:               int64(ru.Oublock),
+			VoluntaryCtxSwitches:   int64(ru.Nvcsw),
+			InvoluntaryCtxSwitches: int64(ru.Nivcsw),
+		}
+	}
+	return status
+}
+
+// watchOOM starts watching the memory cgroup of the container for OOM kills.
+// The returned function reports whether an OOM kill happened since the watch
+// started, it must be called before the container's cgroups are removed.
+func watchOOM(state *libcontainer.State) func() bool {
+	var notified int32
+	if oom, err := libcontainer.NotifyOnOOM(state); err == nil {
+		go func() {
+			for range oom {
+				atomic.StoreInt32(&notified, 1)
+			}
+		}()
+	}
+	return func() bool {
+		if atomic.LoadInt32(&notified) == 1 {
+			return true
+		}
+		// the notification may not have been delivered yet when the init process
+		// is collected, newer kernels also keep a count of the OOM kills
+		return oomKillCount(state.CgroupPaths["memory"]) > 0
+	}
+}
+
+// oomKillCount returns the oom_kill counter of the memory cgroup at path, 0
+// is returned if the kernel does not keep the counter.
+func oomKillCount(path string) int {
+	if path == "" {
+		return 0
+	}
+	f, err := os.Open(filepath.Join(path, "memory.oom_control"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			n, _ := strconv.Atoi(fields[1])
+			return n
+		}
+	}
+	return 0
+}
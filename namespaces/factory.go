@@ -0,0 +1,224 @@
+// +build linux
+
+package namespaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/reexec"
+	"github.com/docker/libcontainer/utils"
+)
+
+const (
+	configFilename  = "container.json"
+	createdFilename = "created.json"
+)
+
+var (
+	idRegex  = regexp.MustCompile(`^[\w]+$`)
+	maxIdLen = 1024
+)
+
+// New returns a linux based container factory rooted at the root directory.
+// Every container created by the factory gets its own directory under root,
+// named after the container's id, holding its container.json and state.json files.
+//
+// initPath is the binary that is re-executed to run the init of a container and
+// the processes joining it.  It has to call reexec.Init at the top of its main
+// function so that the initializers registered by the package are run.  If
+// initPath is empty the running binary, /proc/self/exe, is used.
+func New(root, initPath string) (libcontainer.Factory, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	if initPath == "" {
+		initPath = reexec.Self()
+	}
+	return &linuxFactory{
+		root:     root,
+		initPath: initPath,
+	}, nil
+}
+
+// linuxFactory implements the libcontainer.Factory interface for linux based systems.
+type linuxFactory struct {
+	// root is the directory where the containers' directories are stored
+	root string
+
+	// initPath is the binary re-executed to setup the container's namespaces
+	initPath string
+}
+
+func (l *linuxFactory) Create(id string, config *libcontainer.Config) (libcontainer.Container, libcontainer.Error) {
+	if err := validateId(id); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, libcontainer.NewGenericError(fmt.Errorf("config for container %q is nil", id), libcontainer.ConfigInvalid)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, libcontainer.NewGenericError(err, libcontainer.ConfigInvalid)
+	}
+
+	// the version is set on a copy so that the caller's config is left untouched
+	copied := *config
+	copied.Version = libcontainer.ConfigVersion
+	config = &copied
+
+	containerRoot := filepath.Join(l.root, id)
+	// Mkdir fails if the directory exists so it is used to reserve the id
+	if err := os.Mkdir(containerRoot, 0700); err != nil {
+		if os.IsExist(err) {
+			return nil, libcontainer.NewGenericError(fmt.Errorf("container with id %q already exists", id), libcontainer.IdInUse)
+		}
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	if err := utils.WriteJSON(filepath.Join(containerRoot, createdFilename), time.Now()); err != nil {
+		os.RemoveAll(containerRoot)
+		return nil, libcontainer.NewSystemError(err)
+	}
+	// the config is written last as its presence marks the container as created
+	if err := utils.WriteJSON(filepath.Join(containerRoot, configFilename), config); err != nil {
+		os.RemoveAll(containerRoot)
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	return newLinuxContainer(id, containerRoot, l.initPath, config), nil
+}
+
+func (l *linuxFactory) Load(id string) (libcontainer.Container, libcontainer.Error) {
+	if err := validateId(id); err != nil {
+		return nil, err
+	}
+
+	containerRoot := filepath.Join(l.root, id)
+	config, err := readConfig(containerRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, libcontainer.NewGenericError(fmt.Errorf("container with id %q does not exist", id), libcontainer.ContainerDestroyed)
+		}
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	return newLinuxContainer(id, containerRoot, l.initPath, config), nil
+}
+
+func (l *linuxFactory) Reconcile() ([]string, libcontainer.Error) {
+	dirs, err := ioutil.ReadDir(l.root)
+	if err != nil {
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	destroyed := []string{}
+	for _, dir := range dirs {
+		if !dir.IsDir() || validateId(dir.Name()) != nil {
+			continue
+		}
+
+		container, err := l.Load(dir.Name())
+		if err != nil {
+			// the container was destroyed since the directory was listed
+			if err.Code() == libcontainer.ContainerDestroyed {
+				continue
+			}
+			return destroyed, err
+		}
+
+		ok, err := container.(*linuxContainer).reconcile()
+		if err != nil {
+			if err.Code() == libcontainer.ContainerDestroyed {
+				continue
+			}
+			return destroyed, err
+		}
+		if ok {
+			destroyed = append(destroyed, dir.Name())
+		}
+	}
+	return destroyed, nil
+}
+
+func (l *linuxFactory) List(filter *libcontainer.ListFilter) ([]*libcontainer.ContainerInfo, libcontainer.Error) {
+	dirs, err := ioutil.ReadDir(l.root)
+	if err != nil {
+		return nil, libcontainer.NewSystemError(err)
+	}
+
+	containers := []*libcontainer.ContainerInfo{}
+	for _, dir := range dirs {
+		if !dir.IsDir() || validateId(dir.Name()) != nil {
+			continue
+		}
+
+		container, err := l.Load(dir.Name())
+		if err != nil {
+			// the container was destroyed since the directory was listed
+			if err.Code() == libcontainer.ContainerDestroyed {
+				continue
+			}
+			return nil, err
+		}
+
+		info, err := container.(*linuxContainer).info()
+		if err != nil {
+			if err.Code() == libcontainer.ContainerDestroyed {
+				continue
+			}
+			return nil, err
+		}
+		if filter.Matches(info) {
+			containers = append(containers, info)
+		}
+	}
+	// ReadDir sorts the directories by name so the containers are sorted by id
+	return containers, nil
+}
+
+// validateId ensures that the id contains only letters, digits and underscores
+// and is at most maxIdLen characters long.
+func validateId(id string) libcontainer.Error {
+	if !idRegex.MatchString(id) || len(id) > maxIdLen {
+		return libcontainer.NewGenericError(fmt.Errorf("invalid id format %q", id), libcontainer.InvalidIdFormat)
+	}
+	return nil
+}
+
+func readConfig(containerRoot string) (*libcontainer.Config, error) {
+	f, err := os.Open(filepath.Join(containerRoot, configFilename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return libcontainer.LoadConfig(f)
+}
+
+// readCreated returns the time the container was created.  The modification time
+// of the container's directory is used for containers created before the time
+// was recorded.
+func readCreated(containerRoot string) (time.Time, error) {
+	var created time.Time
+	f, err := os.Open(filepath.Join(containerRoot, createdFilename))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return created, err
+		}
+		fi, err := os.Stat(containerRoot)
+		if err != nil {
+			return created, err
+		}
+		return fi.ModTime(), nil
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&created)
+	return created, err
+}
@@ -0,0 +1,301 @@
+// +build linux
+
+package namespaces
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/system"
+)
+
+func newTestFactory(t *testing.T) (libcontainer.Factory, string) {
+	// containers of non rootless configs can only be started by root
+	if os.Geteuid() != 0 {
+		t.Skip("the factory tests require root")
+	}
+	root, err := ioutil.TempDir("", "libcontainer-factory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory, err := New(root, "")
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal(err)
+	}
+	return factory, root
+}
+
+func TestFactoryCreateAndLoad(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	config := &libcontainer.Config{
+		Hostname:   "koye",
+		Namespaces: libcontainer.Namespaces{{Type: libcontainer.NEWUTS}},
+	}
+	container, err := factory.Create("test_1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.ID() != "test_1" {
+		t.Fatalf("expected id test_1 but received %q", container.ID())
+	}
+	if config.Version != 0 {
+		t.Fatalf("expected the caller's config to be left untouched but its version is %d", config.Version)
+	}
+	if container.Config().Version != libcontainer.ConfigVersion {
+		t.Fatalf("expected version %d but received %d", libcontainer.ConfigVersion, container.Config().Version)
+	}
+	if _, err := os.Stat(filepath.Join(root, "test_1", configFilename)); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := factory.Load("test_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Config().Hostname != "koye" {
+		t.Fatalf("expected hostname koye but received %q", loaded.Config().Hostname)
+	}
+}
+
+func TestFactoryCreateIdInUse(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	if _, err := factory.Create("test", &libcontainer.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := factory.Create("test", &libcontainer.Config{})
+	if err == nil {
+		t.Fatal("expected error creating a container with an id in use")
+	}
+	if err.Code() != libcontainer.IdInUse {
+		t.Fatalf("expected IdInUse but received %d", err.Code())
+	}
+}
+
+func TestFactoryInvalidId(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	for _, id := range []string{"", "a-b", "../test", "a b", strings.Repeat("a", maxIdLen+1)} {
+		_, err := factory.Create(id, &libcontainer.Config{})
+		if err == nil {
+			t.Fatalf("expected error creating a container with id %q", id)
+		}
+		if err.Code() != libcontainer.InvalidIdFormat {
+			t.Fatalf("expected InvalidIdFormat for id %q but received %d", id, err.Code())
+		}
+	}
+}
+
+func TestFactoryCreateNilConfig(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	_, err := factory.Create("test", nil)
+	if err == nil {
+		t.Fatal("expected error creating a container without a config")
+	}
+	if err.Code() != libcontainer.ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid but received %d", err.Code())
+	}
+	if _, err := os.Stat(filepath.Join(root, "test")); !os.IsNotExist(err) {
+		t.Fatal("expected container directory to not exist")
+	}
+}
+
+func TestFactoryCreateInvalidConfig(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	_, err := factory.Create("test", &libcontainer.Config{Hostname: "koye"})
+	if err == nil {
+		t.Fatal("expected error creating a container with an invalid config")
+	}
+	if err.Code() != libcontainer.ConfigInvalid {
+		t.Fatalf("expected ConfigInvalid but received %d", err.Code())
+	}
+	if _, err := os.Stat(filepath.Join(root, "test")); !os.IsNotExist(err) {
+		t.Fatal("expected container directory to not exist")
+	}
+}
+
+func TestFactoryLoadNotExists(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	_, err := factory.Load("nocontainer")
+	if err == nil {
+		t.Fatal("expected error loading a container that does not exist")
+	}
+	if err.Code() != libcontainer.ContainerDestroyed {
+		t.Fatalf("expected ContainerDestroyed but received %d", err.Code())
+	}
+}
+
+func TestFactoryReconcile(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	for _, id := range []string{"stale", "running", "stopped"} {
+		if _, err := factory.Create(id, &libcontainer.Config{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the pid of an exited process stands in for an init that died without
+	// removing its state
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := libcontainer.SaveState(filepath.Join(root, "stale"), &libcontainer.State{InitPid: cmd.Process.Pid, InitStartTime: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a container with cgroups whose state records no cgroup paths, as for a rootless
+	// container, and whose init pid was reused by an unrelated process
+	if _, err := factory.Create("reused", &libcontainer.Config{Cgroups: &cgroups.Cgroup{Name: "reused"}}); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := exec.Command("sleep", "10")
+	if err := unrelated.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer unrelated.Process.Kill()
+	if err := libcontainer.SaveState(filepath.Join(root, "reused"), &libcontainer.State{InitPid: unrelated.Process.Pid, InitStartTime: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	started, err := system.GetProcessStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := libcontainer.SaveState(filepath.Join(root, "running"), &libcontainer.State{InitPid: os.Getpid(), InitStartTime: started}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, lerr := factory.Load("stale")
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	state, lerr := stale.RunState()
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	if *state != libcontainer.Stopped {
+		t.Fatalf("expected the stale container to be stopped but received state %s", *state)
+	}
+
+	destroyed, lerr := factory.Reconcile()
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	if !reflect.DeepEqual(destroyed, []string{"reused", "stale"}) {
+		t.Fatalf("expected only the stale containers to be destroyed but received %v", destroyed)
+	}
+	if state, err := system.GetProcessState(unrelated.Process.Pid); err != nil || state == "Z" {
+		t.Fatalf("expected the process reusing the init's pid to be left alone but received state %q, %v", state, err)
+	}
+
+	if _, err := factory.Load("stale"); err == nil || err.Code() != libcontainer.ContainerDestroyed {
+		t.Fatalf("expected the stale container to be destroyed but received %v", err)
+	}
+	for _, id := range []string{"running", "stopped"} {
+		if _, err := factory.Load(id); err != nil {
+			t.Fatalf("expected container %q to be kept but received %s", id, err)
+		}
+	}
+}
+
+func TestFactoryList(t *testing.T) {
+	factory, root := newTestFactory(t)
+	defer os.RemoveAll(root)
+
+	for id, labels := range map[string]map[string]string{
+		"web":   {"owner": "alice", "job": "1"},
+		"db":    {"owner": "bob"},
+		"cache": nil,
+	} {
+		if _, err := factory.Create(id, &libcontainer.Config{RootFs: root, Labels: labels}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	started, err := system.GetProcessStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := libcontainer.SaveState(filepath.Join(root, "web"), &libcontainer.State{InitPid: os.Getpid(), InitStartTime: started}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := libcontainer.SaveExitStatus(filepath.Join(root, "db"), &libcontainer.ExitStatus{ExitCode: -1, Signal: 9, OOMKilled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	containers, lerr := factory.List(nil)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	ids := []string{}
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"cache", "db", "web"}) {
+		t.Fatalf("expected every container sorted by id but received %v", ids)
+	}
+
+	web := containers[2]
+	if web.State != libcontainer.Running || web.InitPid != os.Getpid() {
+		t.Fatalf("expected web to be running with pid %d but received %s with pid %d", os.Getpid(), web.State, web.InitPid)
+	}
+	if web.RootFs != root || web.Labels["owner"] != "alice" {
+		t.Fatalf("expected the config of web but received %+v", web)
+	}
+	if web.Created.IsZero() || time.Since(web.Created) > time.Minute {
+		t.Fatalf("expected web to be created recently but received %s", web.Created)
+	}
+	if db := containers[1]; db.State != libcontainer.Stopped || db.InitPid != 0 {
+		t.Fatalf("expected db to be stopped but received %s with pid %d", db.State, db.InitPid)
+	}
+	if db := containers[1]; db.ExitStatus == nil || db.ExitStatus.String() != "killed by signal 9 (oom killed)" {
+		t.Fatalf("expected the exit status of db but received %+v", db.ExitStatus)
+	}
+	if containers[0].ExitStatus != nil || web.ExitStatus != nil {
+		t.Fatal("expected no exit status for a container that never exited")
+	}
+
+	for _, test := range []struct {
+		filter *libcontainer.ListFilter
+		ids    []string
+	}{
+		{&libcontainer.ListFilter{States: []libcontainer.RunState{libcontainer.Stopped}}, []string{"cache", "db"}},
+		{&libcontainer.ListFilter{Labels: map[string]string{"owner": "alice"}}, []string{"web"}},
+		{&libcontainer.ListFilter{Labels: map[string]string{"owner": "alice", "job": "2"}}, []string{}},
+		{&libcontainer.ListFilter{States: []libcontainer.RunState{libcontainer.Running}, Labels: map[string]string{"owner": "bob"}}, []string{}},
+	} {
+		containers, err := factory.List(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, c := range containers {
+			ids = append(ids, c.ID)
+		}
+		if !reflect.DeepEqual(ids, test.ids) {
+			t.Fatalf("expected %v for filter %+v but received %v", test.ids, test.filter, ids)
+		}
+	}
+}
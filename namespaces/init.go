@@ -3,10 +3,10 @@
 package namespaces
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 
@@ -30,17 +30,16 @@ import (
 // and other options required for the new container.
 // The caller of Init function has to ensure that the go runtime is locked to an OS thread
 // (using runtime.LockOSThread) else system calls like setns called within Init may not work as intended.
+// The config sent by Exec over the pipe replaces container, which may be nil as the container's directory
+// is not readable by the init of a container with a user namespace.
 func Init(container *libcontainer.Config, uncleanRootfs, consolePath string, pipe *os.File, args []string) (err error) {
+	// stage names the step of the container's setup reported with a failure
+	stage := "config"
 	defer func() {
 		// if we have an error during the initialization of the container's init then send it back to the
 		// parent process in the form of an initError.
 		if err != nil {
-			// ensure that any data sent from the parent is consumed so it doesn't
-			// receive ECONNRESET when the child writes to the pipe.
-			ioutil.ReadAll(pipe)
-			if err := json.NewEncoder(pipe).Encode(initError{
-				Message: err.Error(),
-			}); err != nil {
+			if err := writeSync(pipe, procError, newInitError(stage, err)); err != nil {
 				panic(err)
 			}
 		}
@@ -48,6 +47,20 @@ func Init(container *libcontainer.Config, uncleanRootfs, consolePath string, pip
 		pipe.Close()
 	}()
 
+	// We always read this as it is a way to sync with the parent as well
+	var iconfig *initConfig
+	if err := readSync(pipe, procConfig, &iconfig); err != nil {
+		return err
+	}
+	// the parent's config includes the overrides of the process being started
+	if iconfig.Config != nil {
+		container = iconfig.Config
+	}
+	if container == nil {
+		return libcontainer.NewGenericError(fmt.Errorf("no config for the container's init"), libcontainer.ConfigInvalid)
+	}
+	networkState := iconfig.NetworkState
+
 	rootfs, err := utils.ResolveRootfs(uncleanRootfs)
 	if err != nil {
 		return err
@@ -58,100 +71,131 @@ func Init(container *libcontainer.Config, uncleanRootfs, consolePath string, pip
 	if err := LoadContainerEnvironment(container); err != nil {
 		return err
 	}
-
-	// We always read this as it is a way to sync with the parent as well
-	var networkState *network.NetworkState
-	if err := json.NewDecoder(pipe).Decode(&networkState); err != nil {
-		return err
-	}
+	stage = "namespaces"
 	// join any namespaces via a path to the namespace fd if provided
 	if err := joinExistingNamespaces(container.Namespaces); err != nil {
 		return err
 	}
+	// the parent moved the init into the container's cgroups before sending the config
+	// so that they become the root of the new cgroup namespace
+	if GetNamespaceFlags(container.Namespaces, true)&system.CLONE_NEWCGROUP != 0 {
+		if err := syscall.Unshare(system.CLONE_NEWCGROUP); err != nil {
+			return libcontainer.NewSystemErrorWithCause(err, "unshare cgroup namespace")
+		}
+	}
+	stage = "console"
 	if consolePath != "" {
 		if err := console.OpenAndDup(consolePath); err != nil {
 			return err
 		}
 	}
 	if _, err := syscall.Setsid(); err != nil {
-		return fmt.Errorf("setsid %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setsid")
 	}
 	if consolePath != "" {
 		if err := system.Setctty(); err != nil {
-			return fmt.Errorf("setctty %s", err)
+			return libcontainer.NewSystemErrorWithCause(err, "setctty")
 		}
 	}
 
+	stage = "network"
 	cloneFlags := GetNamespaceFlags(container.Namespaces, false)
 
-	if (cloneFlags & syscall.CLONE_NEWNET) == 0 &&
-            (len(container.Networks) != 0 || len(container.Routes) != 0) {
-		return fmt.Errorf("unable to apply network parameters without network namespace")
+	if (cloneFlags&syscall.CLONE_NEWNET) == 0 &&
+		(len(container.Networks) != 0 || len(container.Routes) != 0) {
+		return libcontainer.NewGenericError(fmt.Errorf("unable to apply network parameters without network namespace"), libcontainer.ConfigInvalid)
 	}
 	if err := setupNetwork(container, networkState); err != nil {
-		return fmt.Errorf("setup networking %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setup networking")
 	}
 	if err := setupRoute(container); err != nil {
-		return fmt.Errorf("setup route %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setup route")
 	}
 
+	stage = "rlimits"
 	if err := setupRlimits(container); err != nil {
-		return fmt.Errorf("setup rlimits %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setup rlimits")
 	}
 
 	label.Init()
 
+	stage = "mounts"
+
 	if (cloneFlags & syscall.CLONE_NEWNS) == 0 {
 		if container.MountConfig != nil {
-			fmt.Errorf("mount_config is set without a mount namespace");
+			return libcontainer.NewGenericError(fmt.Errorf("mount_config is set without a mount namespace"), libcontainer.ConfigInvalid)
 		}
 	} else if err := mount.InitializeMountNamespace(rootfs,
-			consolePath,
-			container.RestrictSys,
-			(*mount.MountConfig)(container.MountConfig)); err != nil {
-		return fmt.Errorf("setup mount namespace %s", err)
+		consolePath,
+		container.RestrictSys,
+		(*mount.MountConfig)(container.MountConfig)); err != nil {
+		return libcontainer.NewSystemErrorWithCause(err, "setup mount namespace")
 	}
 
+	stage = "hostname"
 	if container.Hostname != "" {
 		if (cloneFlags & syscall.CLONE_NEWUTS) == 0 {
-			return fmt.Errorf("unable to set the hostname without UTS namespace");
+			return libcontainer.NewGenericError(fmt.Errorf("unable to set the hostname without UTS namespace"), libcontainer.ConfigInvalid)
 		}
 		if err := syscall.Sethostname([]byte(container.Hostname)); err != nil {
-			return fmt.Errorf("unable to sethostname %q: %s", container.Hostname, err)
+			return libcontainer.NewSystemErrorWithCause(err, fmt.Sprintf("unable to sethostname %q", container.Hostname))
 		}
 	}
 
+	// sysctls are written before /proc/sys is made read-only
+	stage = "sysctl"
+	if err := setupSysctl(container); err != nil {
+		return err
+	}
+
+	stage = "security"
 	if err := apparmor.ApplyProfile(container.AppArmorProfile); err != nil {
-		return fmt.Errorf("set apparmor profile %s: %s", container.AppArmorProfile, err)
+		return libcontainer.NewSystemErrorWithCause(err, fmt.Sprintf("set apparmor profile %s", container.AppArmorProfile))
 	}
 
 	if err := label.SetProcessLabel(container.ProcessLabel); err != nil {
-		return fmt.Errorf("set process label %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "set process label")
 	}
 
 	// TODO: (crosbymichael) make this configurable at the Config level
 	if container.RestrictSys {
 		if (cloneFlags & syscall.CLONE_NEWNS) == 0 {
-			return fmt.Errorf("unable to restrict access to syctl without mount namespace");
+			return libcontainer.NewGenericError(fmt.Errorf("unable to restrict access to syctl without mount namespace"), libcontainer.ConfigInvalid)
 		}
 		if err := restrict.Restrict("proc/sys", "proc/sysrq-trigger", "proc/irq", "proc/bus"); err != nil {
 			return err
 		}
 	}
 
+	// the container is setup, the parent runs the prestart hooks before the user's
+	// process is executed
+	stage = "hooks"
+	if err := writeSync(pipe, procReady, nil); err != nil {
+		return err
+	}
+	if err := readSync(pipe, procRun, nil); err != nil {
+		return err
+	}
+
+	stage = "finalize"
 	pdeathSignal, err := system.GetParentDeathSignal()
 	if err != nil {
-		return fmt.Errorf("get parent death signal %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "get parent death signal")
 	}
 
 	if err := FinalizeNamespace(container); err != nil {
-		return fmt.Errorf("finalize namespace %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "finalize namespace")
 	}
 
 	// FinalizeNamespace can change user/group which clears the parent death
 	// signal, so we restore it here.
 	if err := RestoreParentDeathSignal(pdeathSignal); err != nil {
-		return fmt.Errorf("restore parent death signal %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "restore parent death signal")
+	}
+
+	stage = "exec"
+	if container.BuiltinInit {
+		return runBuiltinInit(args, consolePath != "", pipe)
 	}
 
 	return system.Execv(args[0], args[0:], os.Environ())
@@ -165,7 +209,7 @@ func RestoreParentDeathSignal(old int) error {
 
 	current, err := system.GetParentDeathSignal()
 	if err != nil {
-		return fmt.Errorf("get parent death signal %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "get parent death signal")
 	}
 
 	if old == current {
@@ -173,7 +217,7 @@ func RestoreParentDeathSignal(old int) error {
 	}
 
 	if err := system.ParentDeathSignal(uintptr(old)); err != nil {
-		return fmt.Errorf("set parent death signal %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "set parent death signal")
 	}
 
 	// Signal self if parent is already dead. Does nothing if running in a new
@@ -206,25 +250,28 @@ func SetupUser(u string) error {
 
 	execUser, err := user.GetExecUserPath(u, &defaultExecUser, passwdPath, groupPath)
 	if err != nil {
-		return fmt.Errorf("get supplementary groups %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "get supplementary groups")
 	}
 
-	if err := syscall.Setgroups(execUser.Sgids); err != nil {
-		return fmt.Errorf("setgroups %s", err)
+	// the groups cannot be changed in a user namespace created by an unprivileged user
+	if !setgroupsDenied() {
+		if err := syscall.Setgroups(execUser.Sgids); err != nil {
+			return libcontainer.NewSystemErrorWithCause(err, "setgroups")
+		}
 	}
 
 	if err := system.Setgid(execUser.Gid); err != nil {
-		return fmt.Errorf("setgid %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setgid")
 	}
 
 	if err := system.Setuid(execUser.Uid); err != nil {
-		return fmt.Errorf("setuid %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setuid")
 	}
 
 	// if we didn't get HOME already, set it based on the user's HOME
 	if envHome := os.Getenv("HOME"); envHome == "" {
 		if err := os.Setenv("HOME", execUser.Home); err != nil {
-			return fmt.Errorf("set HOME %s", err)
+			return libcontainer.NewSystemErrorWithCause(err, "set HOME")
 		}
 	}
 
@@ -262,7 +309,18 @@ func setupRlimits(container *libcontainer.Config) error {
 	for _, rlimit := range container.Rlimits {
 		l := &syscall.Rlimit{Max: rlimit.Hard, Cur: rlimit.Soft}
 		if err := syscall.Setrlimit(rlimit.Type, l); err != nil {
-			return fmt.Errorf("error setting rlimit type %v: %v", rlimit.Type, err)
+			return libcontainer.NewSystemErrorWithCause(err, fmt.Sprintf("error setting rlimit type %v", rlimit.Type))
+		}
+	}
+	return nil
+}
+
+// setupSysctl writes the container's sysctls to /proc/sys.
+func setupSysctl(container *libcontainer.Config) error {
+	for key, value := range container.Sysctl {
+		path := filepath.Join("/proc/sys", strings.Replace(key, ".", "/", -1))
+		if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+			return libcontainer.NewSystemErrorWithCause(err, fmt.Sprintf("set sysctl %s to %q", key, value))
 		}
 	}
 	return nil
@@ -276,35 +334,35 @@ func FinalizeNamespace(container *libcontainer.Config) error {
 	// inherited are marked close-on-exec so they stay out of the
 	// container
 	if err := utils.CloseExecFrom(3); err != nil {
-		return fmt.Errorf("close open file descriptors %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "close open file descriptors")
 	}
 
 	// drop capabilities in bounding set before changing user
 	if err := capabilities.DropBoundingSet(container.Capabilities); err != nil {
-		return fmt.Errorf("drop bounding set %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "drop bounding set")
 	}
 
 	// preserve existing capabilities while we change users
 	if err := system.SetKeepCaps(); err != nil {
-		return fmt.Errorf("set keep caps %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "set keep caps")
 	}
 
 	if err := SetupUser(container.User); err != nil {
-		return fmt.Errorf("setup user %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "setup user")
 	}
 
 	if err := system.ClearKeepCaps(); err != nil {
-		return fmt.Errorf("clear keep caps %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "clear keep caps")
 	}
 
 	// drop all other capabilities
 	if err := capabilities.DropCapabilities(container.Capabilities); err != nil {
-		return fmt.Errorf("drop capabilities %s", err)
+		return libcontainer.NewSystemErrorWithCause(err, "drop capabilities")
 	}
 
 	if container.WorkingDir != "" {
 		if err := syscall.Chdir(container.WorkingDir); err != nil {
-			return fmt.Errorf("chdir to %s %s", container.WorkingDir, err)
+			return libcontainer.NewSystemErrorWithCause(err, fmt.Sprintf("chdir to %s", container.WorkingDir))
 		}
 	}
 
@@ -316,7 +374,7 @@ func LoadContainerEnvironment(container *libcontainer.Config) error {
 	for _, pair := range container.Env {
 		p := strings.SplitN(pair, "=", 2)
 		if len(p) < 2 {
-			return fmt.Errorf("invalid environment '%v'", pair)
+			return libcontainer.NewGenericError(fmt.Errorf("invalid environment '%v'", pair), libcontainer.ConfigInvalid)
 		}
 		if err := os.Setenv(p[0], p[1]); err != nil {
 			return err
@@ -0,0 +1,23 @@
+// +build linux
+
+package namespaces
+
+import (
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+func TestSetupRlimitsError(t *testing.T) {
+	container := &libcontainer.Config{
+		Rlimits: []libcontainer.Rlimit{{Type: 1000, Hard: 1, Soft: 1}},
+	}
+
+	err := setupRlimits(container)
+	if err == nil {
+		t.Fatal("expected an error for an unknown rlimit type")
+	}
+	if err.Error() != "error setting rlimit type 1000: invalid argument" {
+		t.Fatalf("unexpected error message %q", err.Error())
+	}
+}
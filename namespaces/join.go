@@ -0,0 +1,138 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/libcontainer"
+)
+
+// namespaceFiles are the names of the namespace files in /proc/<pid>/ns.
+var namespaceFiles = map[libcontainer.NamespaceType]string{
+	libcontainer.NEWNET:    "net",
+	libcontainer.NEWNS:     "mnt",
+	libcontainer.NEWUSER:   "user",
+	libcontainer.NEWIPC:    "ipc",
+	libcontainer.NEWUTS:    "uts",
+	libcontainer.NEWPID:    "pid",
+	libcontainer.NEWCGROUP: "cgroup",
+}
+
+// resolveNamespaces returns a copy of config where the namespaces referencing other
+// containers have the path of the namespace file of the containers' init processes,
+// or of the file persisted in the containers' directories.  The persisted files are
+// used once the init process of a container exited.
+// The referenced containers are locked until release is called so that they are not
+// stopped or destroyed before the new init process joined their namespaces.
+func (c *linuxContainer) resolveNamespaces(config *libcontainer.Config) (resolved *libcontainer.Config, release func(), err libcontainer.Error) {
+	var locks []*FileLock
+	unlock := func() {
+		for _, l := range locks {
+			l.Unlock()
+		}
+	}
+	defer func() {
+		if err != nil {
+			unlock()
+		}
+	}()
+
+	copied := *config
+	copied.Namespaces = make(libcontainer.Namespaces, len(config.Namespaces))
+	copy(copied.Namespaces, config.Namespaces)
+
+	for i, ns := range copied.Namespaces {
+		if ns.Container == "" {
+			continue
+		}
+		if ns.Container == c.id {
+			return nil, nil, libcontainer.NewGenericError(fmt.Errorf("container %q cannot join its own namespaces", c.id), libcontainer.ConfigInvalid)
+		}
+		if err := validateId(ns.Container); err != nil {
+			return nil, nil, err
+		}
+
+		root := filepath.Join(filepath.Dir(c.root), ns.Container)
+		l, lerr := RLock(root)
+		if lerr != nil {
+			if os.IsNotExist(lerr) {
+				return nil, nil, libcontainer.NewGenericError(fmt.Errorf("container %q whose namespace %s is joined does not exist", ns.Container, ns.Type), libcontainer.ContainerDestroyed)
+			}
+			return nil, nil, libcontainer.NewSystemError(lerr)
+		}
+		locks = append(locks, l)
+
+		state, serr := libcontainer.GetState(root)
+		if serr != nil && !os.IsNotExist(serr) {
+			return nil, nil, libcontainer.NewSystemError(serr)
+		}
+		if state == nil || !initAlive(state) {
+			// persisted namespaces outlive the init process
+			if path := persistedNamespace(root, ns.Type); path != "" {
+				copied.Namespaces[i].Path = path
+				continue
+			}
+			return nil, nil, libcontainer.NewGenericError(fmt.Errorf("container %q whose namespace %s is joined is not running", ns.Container, ns.Type), libcontainer.ContainerNotRunning)
+		}
+		if path, ok := state.NamespacePaths[ns.Type]; ok {
+			copied.Namespaces[i].Path = path
+			continue
+		}
+		copied.Namespaces[i].Path = fmt.Sprintf("/proc/%d/ns/%s", state.InitPid, namespaceFiles[ns.Type])
+	}
+	return &copied, unlock, nil
+}
+
+// joinedNamespaces returns the types of the namespaces joined from other containers
+// mapped to the containers' ids.
+func joinedNamespaces(config *libcontainer.Config) map[libcontainer.NamespaceType]string {
+	var joined map[libcontainer.NamespaceType]string
+	for _, ns := range config.Namespaces {
+		if ns.Container == "" {
+			continue
+		}
+		if joined == nil {
+			joined = make(map[libcontainer.NamespaceType]string)
+		}
+		joined[ns.Type] = ns.Container
+	}
+	return joined
+}
+
+// errInUse returns a ContainerInUse error if running containers of the same factory
+// joined the container's namespaces.
+func (c *linuxContainer) errInUse() libcontainer.Error {
+	dirs, err := ioutil.ReadDir(filepath.Dir(c.root))
+	if err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+
+	var users []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || dir.Name() == c.id {
+			continue
+		}
+		// the state is written atomically so it is read without locking the
+		// container, which may be waiting for this container's lock to start
+		state, err := libcontainer.GetState(filepath.Join(filepath.Dir(c.root), dir.Name()))
+		if err != nil || !initAlive(state) {
+			continue
+		}
+		for _, id := range state.JoinedNamespaces {
+			if id == c.id {
+				users = append(users, dir.Name())
+				break
+			}
+		}
+	}
+
+	if len(users) > 0 {
+		return libcontainer.NewGenericError(fmt.Errorf("namespaces of container %q are in use by %s", c.id, strings.Join(users, ", ")), libcontainer.ContainerInUse)
+	}
+	return nil
+}
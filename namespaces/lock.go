@@ -0,0 +1,58 @@
+// +build linux
+
+package namespaces
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileLock is an advisory lock held with flock(2) on a container's directory.
+// Processes operating on the same container directory are serialized by the
+// lock, it does not prevent access by processes that do not take it.
+type FileLock struct {
+	f *os.File
+}
+
+// Lock takes an exclusive lock on the directory at path, blocking until the lock
+// is available.  The exclusive lock is taken by operations that change the state
+// of the container.
+func Lock(path string) (*FileLock, error) {
+	return lockPath(path, syscall.LOCK_EX)
+}
+
+// RLock takes a shared lock on the directory at path, blocking until no exclusive
+// lock is held.  The shared lock is taken by operations that only read the state
+// of the container.
+func RLock(path string) (*FileLock, error) {
+	return lockPath(path, syscall.LOCK_SH)
+}
+
+func lockPath(path string, how int) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "flock", Path: path, Err: err}
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock.  Calling Unlock more than once is a no-op.
+func (l *FileLock) Unlock() error {
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
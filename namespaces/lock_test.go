@@ -0,0 +1,50 @@
+// +build linux
+
+package namespaces
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestLockExcludesOtherLocks(t *testing.T) {
+	root, err := ioutil.TempDir("", "libcontainer-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	l, err := Lock(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != syscall.EWOULDBLOCK {
+		t.Fatalf("expected a shared lock to be refused while the exclusive lock is held but received %v", err)
+	}
+
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("expected unlocking twice to be a no-op but received %s", err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		t.Fatalf("expected a shared lock once the exclusive lock is released but received %s", err)
+	}
+}
+
+func TestLockMissingDirectory(t *testing.T) {
+	if _, err := RLock("/does/not/exist"); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error but received %v", err)
+	}
+}
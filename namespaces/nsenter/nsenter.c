@@ -11,6 +11,7 @@
 #include <stdlib.h>
 #include <string.h>
 #include <sys/prctl.h>
+#include <sys/stat.h>
 #include <sys/types.h>
 #include <unistd.h>
 #include <getopt.h>
@@ -86,7 +87,7 @@ int setns(int fd, int nstype)
 void print_usage()
 {
 	fprintf(stderr,
-		"nsenter --nspid <pid> --console <console> -- cmd1 arg1 arg2...\n");
+		"nsenter --nspid <pid> [--nsdir <dir>] [--pipe <fd>] --console <console> -- cmd1 arg1 arg2...\n");
 }
 
 void nsenter()
@@ -112,13 +113,17 @@ void nsenter()
 	static const struct option longopts[] = {
 		{"nspid", required_argument, NULL, 'n'},
 		{"console", required_argument, NULL, 't'},
+		{"nsdir", required_argument, NULL, 'd'},
+		{"pipe", required_argument, NULL, 'p'},
 		{NULL, 0, NULL, 0}
 	};
 
 	pid_t init_pid = -1;
 	char *init_pid_str = NULL;
 	char *console = NULL;
-	while ((c = getopt_long_only(argc, argv, "n:c:", longopts, NULL)) != -1) {
+	char *nsdir = NULL;
+	int pipefd = -1;
+	while ((c = getopt_long_only(argc, argv, "n:c:d:p:", longopts, NULL)) != -1) {
 		switch (c) {
 		case 'n':
 			init_pid_str = optarg;
@@ -126,6 +131,12 @@ void nsenter()
 		case 't':
 			console = optarg;
 			break;
+		case 'd':
+			nsdir = optarg;
+			break;
+		case 'p':
+			pipefd = strtol(optarg, NULL, 10);
+			break;
 		}
 	}
 
@@ -161,10 +172,14 @@ void nsenter()
 			exit(1);
 		}
 	}
-	// Setns on all supported namespaces.
+	// Setns on all supported namespaces.  The namespace files persisted in the
+	// container's directory are used instead of the init's if provided.
 	char ns_dir[PATH_MAX];
 	memset(ns_dir, 0, PATH_MAX);
-	snprintf(ns_dir, PATH_MAX - 1, "/proc/%d/ns/", init_pid);
+	if (nsdir != NULL)
+		snprintf(ns_dir, PATH_MAX - 1, "%s/", nsdir);
+	else
+		snprintf(ns_dir, PATH_MAX - 1, "/proc/%d/ns/", init_pid);
 
 	int ns_dir_fd;
 	ns_dir_fd = open(ns_dir, O_RDONLY | O_DIRECTORY);
@@ -174,7 +189,9 @@ void nsenter()
 		exit(1);
 	}
 
-	char *namespaces[] = { "ipc", "uts", "net", "pid", "mnt" };
+	// The user namespace is joined first so that the other namespaces, which
+	// are owned by it, can be joined with the capabilities it grants.
+	char *namespaces[] = { "user", "ipc", "uts", "net", "pid", "cgroup", "mnt" };
 	const int num = sizeof(namespaces) / sizeof(char *);
 	int i;
 	for (i = 0; i < num; i++) {
@@ -188,6 +205,16 @@ void nsenter()
 				ns_dir, namespaces[i], strerror(errno));
 			exit(1);
 		}
+		// Joining the current user namespace fails, it is shared by
+		// containers without a user namespace of their own.
+		if (strcmp(namespaces[i], "user") == 0) {
+			struct stat self, target;
+			if (stat("/proc/self/ns/user", &self) == 0
+			    && fstatat(ns_dir_fd, namespaces[i], &target, 0) == 0
+			    && self.st_ino == target.st_ino
+			    && self.st_dev == target.st_dev)
+				continue;
+		}
 
 		int fd = openat(ns_dir_fd, namespaces[i], O_RDONLY);
 		if (fd == -1) {
@@ -234,7 +261,10 @@ void nsenter()
 		// Finish executing, let the Go runtime take over.
 		return;
 	} else {
-		// Parent, wait for the child.
+		// Parent, wait for the child.  The sync pipe is only held by the child
+		// so that the other end sees it closed once the child executes.
+		if (pipefd != -1)
+			close(pipefd);
 		int status = 0;
 		if (waitpid(child, &status, 0) == -1) {
 			fprintf(stderr,
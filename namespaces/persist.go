@@ -0,0 +1,109 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/docker/libcontainer"
+)
+
+// nsDirname is the directory of the container's directory holding its persisted
+// namespace files.
+const nsDirname = "ns"
+
+// persistNamespaces bind mounts the namespace files of the init process with pid
+// into the ns directory of dataPath.  The files of a previous init process are
+// replaced.
+func persistNamespaces(container *libcontainer.Config, pid int, dataPath string) (map[libcontainer.NamespaceType]string, error) {
+	dir := filepath.Join(dataPath, nsDirname)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	paths := make(map[libcontainer.NamespaceType]string)
+	for _, ns := range container.Namespaces {
+		var (
+			name = namespaceFiles[ns.Type]
+			dest = filepath.Join(dir, name)
+		)
+		if err := unmountNamespace(dest); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_RDONLY, 0400)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+
+		source := fmt.Sprintf("/proc/%d/ns/%s", pid, name)
+		if err := syscall.Mount(source, dest, "bind", syscall.MS_BIND, ""); err != nil {
+			return nil, fmt.Errorf("bind mount %s to %s %s", source, dest, err)
+		}
+		paths[ns.Type] = dest
+	}
+	return paths, nil
+}
+
+// unmountNamespaces unmounts the namespace files persisted in dataPath and removes
+// the ns directory.
+func unmountNamespaces(dataPath string) error {
+	dir := filepath.Join(dataPath, nsDirname)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, f := range files {
+		if err := unmountNamespace(filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// unmountNamespace unmounts the namespace file at path if it is mounted.
+func unmountNamespace(path string) error {
+	if err := syscall.Unmount(path, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL && err != syscall.ENOENT {
+		return err
+	}
+	return nil
+}
+
+// persistedNamespace returns the file of the namespace of type t persisted in
+// dataPath, or an empty string if the namespace was not persisted.  The files are
+// kept after the init process exited until the container is destroyed.
+func persistedNamespace(dataPath string, t libcontainer.NamespaceType) string {
+	path := filepath.Join(dataPath, nsDirname, namespaceFiles[t])
+	if !isMountPoint(path) {
+		return ""
+	}
+	return path
+}
+
+// isMountPoint reports whether something is mounted at path.  The files mounted
+// in the ns directory are on another device than the directory.
+func isMountPoint(path string) bool {
+	var file, dir syscall.Stat_t
+	if err := syscall.Stat(path, &file); err != nil {
+		return false
+	}
+	if err := syscall.Stat(filepath.Dir(path), &dir); err != nil {
+		return false
+	}
+	return file.Dev != dir.Dev
+}
+
+// nsDir returns the directory holding the namespace files persisted in state.
+func nsDir(state *libcontainer.State) string {
+	for _, path := range state.NamespacePaths {
+		return filepath.Dir(path)
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+func TestPersistNamespaces(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating namespaces and bind mounts requires root")
+	}
+	root, err := ioutil.TempDir("", "libcontainer-persist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cmd := exec.Command("sleep", "10")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	config := &libcontainer.Config{Namespaces: libcontainer.Namespaces{{Type: libcontainer.NEWIPC}, {Type: libcontainer.NEWUTS}}}
+	paths, err := persistNamespaces(config, cmd.Process.Pid, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unmountNamespaces(root)
+
+	for _, ns := range config.Namespaces {
+		path := paths[ns.Type]
+		if path != filepath.Join(root, nsDirname, namespaceFiles[ns.Type]) {
+			t.Fatalf("unexpected path %q for namespace %s", path, ns.Type)
+		}
+
+		var persisted, proc syscall.Stat_t
+		if err := syscall.Stat(path, &persisted); err != nil {
+			t.Fatal(err)
+		}
+		if err := syscall.Stat(fmt.Sprintf("/proc/%d/ns/%s", cmd.Process.Pid, namespaceFiles[ns.Type]), &proc); err != nil {
+			t.Fatal(err)
+		}
+		if persisted.Ino != proc.Ino || persisted.Dev != proc.Dev {
+			t.Fatalf("expected %s to be the namespace of process %d", path, cmd.Process.Pid)
+		}
+		if persistedNamespace(root, ns.Type) != path {
+			t.Fatalf("expected %s to be found as the persisted namespace %s", path, ns.Type)
+		}
+	}
+	if persistedNamespace(root, libcontainer.NEWNET) != "" {
+		t.Fatal("expected no persisted namespace for a namespace that was not persisted")
+	}
+
+	// the files of a new init replace the previous ones
+	if _, err := persistNamespaces(config, cmd.Process.Pid, root); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unmountNamespaces(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, nsDirname)); !os.IsNotExist(err) {
+		t.Fatalf("expected the ns directory to be removed but received %v", err)
+	}
+}
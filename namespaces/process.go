@@ -0,0 +1,147 @@
+// +build linux
+
+package namespaces
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/docker/libcontainer"
+	consolepkg "github.com/docker/libcontainer/console"
+)
+
+// linuxProcess is a process started inside a linux container.
+type linuxProcess struct {
+	cmd *exec.Cmd
+
+	// the ends of the stdio pipes used by the caller
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	master *os.File
+
+	// the stdio passed to the process and the console path inside the container
+	childStdin  io.Reader
+	childStdout io.Writer
+	childStderr io.Writer
+	console     string
+
+	// the ends of the stdio pipes that belong to the process, they are closed
+	// once the process is started
+	childFiles []*os.File
+
+	done   chan struct{}
+	status *libcontainer.ExitStatus
+	err    libcontainer.Error
+}
+
+// newLinuxProcess sets up the stdio of the process described by config.  A
+// terminal is allocated if config.Tty is set, otherwise pipes are created for
+// the streams that are nil.
+func newLinuxProcess(config *libcontainer.ProcessConfig) (*linuxProcess, error) {
+	p := &linuxProcess{
+		done: make(chan struct{}),
+	}
+
+	if config.Tty {
+		master, console, err := consolepkg.CreateMasterAndConsole()
+		if err != nil {
+			return nil, err
+		}
+		p.master, p.console = master, console
+		return p, nil
+	}
+
+	if config.Stdin != nil {
+		p.childStdin = config.Stdin
+	} else {
+		r, w, err := os.Pipe()
+		if err != nil {
+			p.closePipes()
+			return nil, err
+		}
+		p.childStdin, p.stdin = r, w
+		p.childFiles = append(p.childFiles, r)
+	}
+
+	if config.Stdout != nil {
+		p.childStdout = config.Stdout
+	} else {
+		r, w, err := os.Pipe()
+		if err != nil {
+			p.closePipes()
+			return nil, err
+		}
+		p.childStdout, p.stdout = w, r
+		p.childFiles = append(p.childFiles, w)
+	}
+
+	if config.Stderr != nil {
+		p.childStderr = config.Stderr
+	} else {
+		r, w, err := os.Pipe()
+		if err != nil {
+			p.closePipes()
+			return nil, err
+		}
+		p.childStderr, p.stderr = w, r
+		p.childFiles = append(p.childFiles, w)
+	}
+
+	return p, nil
+}
+
+func (p *linuxProcess) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+func (p *linuxProcess) Wait() (*libcontainer.ExitStatus, libcontainer.Error) {
+	<-p.done
+	return p.status, p.err
+}
+
+func (p *linuxProcess) Signal(sig os.Signal) libcontainer.Error {
+	if err := p.cmd.Process.Signal(sig); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	return nil
+}
+
+func (p *linuxProcess) Stdin() io.WriteCloser {
+	return p.stdin
+}
+
+func (p *linuxProcess) Stdout() io.ReadCloser {
+	return p.stdout
+}
+
+func (p *linuxProcess) Stderr() io.ReadCloser {
+	return p.stderr
+}
+
+func (p *linuxProcess) Terminal() *os.File {
+	return p.master
+}
+
+// closeChildFiles closes the process' ends of the stdio pipes in the caller.
+func (p *linuxProcess) closeChildFiles() {
+	for _, f := range p.childFiles {
+		f.Close()
+	}
+	p.childFiles = nil
+}
+
+// closePipes closes both ends of the stdio pipes and the terminal when the
+// process could not be started.
+func (p *linuxProcess) closePipes() {
+	p.closeChildFiles()
+	for _, c := range []io.Closer{p.stdin, p.stdout, p.stderr} {
+		if c != nil {
+			c.Close()
+		}
+	}
+	if p.master != nil {
+		p.master.Close()
+	}
+}
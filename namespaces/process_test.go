@@ -0,0 +1,43 @@
+// +build linux
+
+package namespaces
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+func TestLinuxProcessPipes(t *testing.T) {
+	process, err := newLinuxProcess(&libcontainer.ProcessConfig{Stderr: os.Stderr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer process.closePipes()
+
+	if process.Stdin() == nil || process.Stdout() == nil {
+		t.Fatal("expected pipes for stdin and stdout")
+	}
+	if process.Stderr() != nil {
+		t.Fatal("expected no pipe for the provided stderr")
+	}
+	if process.Terminal() != nil {
+		t.Fatal("expected no terminal without tty")
+	}
+
+	// the process' end of the stdout pipe is what the caller reads from
+	go func() {
+		process.childStdout.(*os.File).Write([]byte("hello"))
+		process.closeChildFiles()
+	}()
+
+	data, err := ioutil.ReadAll(process.Stdout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("expected to read %q but received %q", "hello", data)
+	}
+}
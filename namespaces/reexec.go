@@ -0,0 +1,95 @@
+// +build linux
+
+package namespaces
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+
+	_ "github.com/docker/libcontainer/namespaces/nsenter"
+	"github.com/docker/libcontainer/reexec"
+)
+
+// The initializers run by the binaries re-executed by Exec, ExecIn and the
+// supervisor of detached containers.  Programs using the package call reexec.Init
+// at the top of main so that they run them.
+func init() {
+	reexec.Register("init", initInitializer)
+	reexec.Register("nsenter-exec", setnsInitializer)
+	reexec.Register("supervise", superviseInitializer)
+}
+
+// initInitializer runs Init in the container's init process started by
+// DefaultCreateCommand.
+func initInitializer() {
+	runtime.LockOSThread()
+
+	rootfs, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pipe, err := envPipe()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the config is read from the pipe, the container's directory is not readable
+	// from inside a user namespace
+	if err := Init(nil, rootfs, os.Getenv("console"), pipe, userArgs()); err != nil {
+		log.Fatalf("unable to initialize for container: %s", err)
+	}
+}
+
+// setnsInitializer runs FinalizeSetns in the process started by ExecIn once the
+// nsenter constructor joined the container's namespaces.
+func setnsInitializer() {
+	runtime.LockOSThread()
+
+	pipe := os.NewFile(3, "pipe")
+	config, err := ReadSetnsConfig(pipe)
+	if err != nil {
+		log.Fatalf("unable to receive config from sync pipe: %s", err)
+	}
+
+	if err := FinalizeSetns(config, pipe, userArgs()); err != nil {
+		log.Fatalf("failed to nsenter: %s", err)
+	}
+}
+
+// superviseInitializer runs Supervise in the supervisor process started for a
+// detached container and exits with the exit code of the container's init.
+func superviseInitializer() {
+	pipe, err := envPipe()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	exitCode, err := Supervise(os.Getenv("data_path"), pipe, userArgs())
+	if err != nil {
+		log.Fatalf("failed to supervise: %s", err)
+	}
+
+	os.Exit(exitCode)
+}
+
+// envPipe returns the sync pipe whose fd is set in the pipe environment variable.
+func envPipe() (*os.File, error) {
+	fd, err := strconv.Atoi(os.Getenv("pipe"))
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "pipe"), nil
+}
+
+// userArgs returns the arguments following "--" in the process' arguments.
+func userArgs() []string {
+	for i, a := range os.Args {
+		if a == "--" {
+			return os.Args[i+1:]
+		}
+	}
+	return []string{}
+}
@@ -0,0 +1,143 @@
+// +build linux
+
+package namespaces
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/netlink"
+	"github.com/docker/libcontainer/system"
+)
+
+// initAlive reports whether the init process recorded in state is still running.
+// The start time of the process is compared with the recorded one so that a pid
+// reused by another process after the init died is not mistaken for the init.
+func initAlive(state *libcontainer.State) bool {
+	started, err := system.GetProcessStartTime(state.InitPid)
+	if err != nil {
+		return false
+	}
+	// states written without a start time can only be checked by pid
+	return state.InitStartTime == "" || started == state.InitStartTime
+}
+
+// ownsState reports whether the state in dataPath was written for the init process
+// pid that started at started.  A missing state is owned by no process.
+func ownsState(dataPath string, pid int, started string) bool {
+	state, err := libcontainer.GetState(dataPath)
+	if err != nil {
+		return false
+	}
+	return state.InitPid == pid && state.InitStartTime == started
+}
+
+// stopKillTimeout is the time Stop waits for the init process to exit after
+// it was sent SIGKILL.
+const stopKillTimeout = 10 * time.Second
+
+// initExited reports whether the init process recorded in state exited.  An
+// init that is a zombie exited but was not yet reaped by its parent.
+func initExited(state *libcontainer.State) bool {
+	if !initAlive(state) {
+		return true
+	}
+	s, err := system.GetProcessState(state.InitPid)
+	return err != nil || s == "Z"
+}
+
+// waitInitExit waits for at most timeout for the init process to exit and
+// reports whether it exited.
+func waitInitExit(state *libcontainer.State, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !initExited(state) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// reconcile destroys the container if its state was left behind by an init
+// process that died without cleaning up, e.g. because the process that started
+// the container crashed.  It reports whether the container was destroyed.
+func (c *linuxContainer) reconcile() (bool, libcontainer.Error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	l, err := c.lock(true)
+	if err != nil {
+		return false, err
+	}
+	defer l.Unlock()
+
+	state, serr := libcontainer.GetState(c.root)
+	if serr != nil {
+		if os.IsNotExist(serr) {
+			return false, nil
+		}
+		return false, libcontainer.NewSystemError(serr)
+	}
+	if initAlive(state) {
+		return false, nil
+	}
+	// the namespaces of a dead init are kept alive by the containers that joined them
+	if err := c.errInUse(); err != nil {
+		if err.Code() == libcontainer.ContainerInUse {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := c.cleanup(state, false); err != nil {
+		return false, libcontainer.NewSystemError(err)
+	}
+	if err := unmountNamespaces(c.root); err != nil {
+		return false, libcontainer.NewSystemError(err)
+	}
+	if err := os.RemoveAll(c.root); err != nil {
+		return false, libcontainer.NewSystemError(err)
+	}
+	c.destroyed = true
+
+	return true, nil
+}
+
+// cleanup kills the processes left in the container's cgroups and removes the
+// cgroups, the host side of the container's veth and the state.json file.  The
+// init process is only killed by pid if it is alive, the pid of a dead init
+// may have been reused by an unrelated process.
+func (c *linuxContainer) cleanup(state *libcontainer.State, alive bool) error {
+	if alive || c.config.Cgroups != nil {
+		if err := c.killAll(state); err != nil {
+			return err
+		}
+	}
+	if err := cgroups.RemovePaths(state.CgroupPaths); err != nil {
+		return err
+	}
+	if err := removeHostVeth(state.NetworkState.VethHost); err != nil {
+		return err
+	}
+	if err := libcontainer.DeleteState(c.root); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeHostVeth deletes the host side of a container's veth pair.  The pair is
+// usually removed by the kernel with the container's network namespace so it is
+// not an error if the interface no longer exists.
+func removeHostVeth(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := net.InterfaceByName(name); err != nil {
+		return nil
+	}
+	return netlink.NetworkLinkDel(name)
+}
@@ -0,0 +1,195 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/reexec"
+)
+
+// Supervise runs the init process of a detached container via Exec and waits for
+// it to exit so that its exit status is recorded in dataPath.  The container's
+// config, including the overrides of the process, is read from the pipe and the
+// pid of the init process, or the error starting it, is sent back.
+//
+// Supervise is called in the supervisor process started by Container.Start for
+// detached processes.  Its stdio is passed on to the init process.
+func Supervise(dataPath string, pipe *os.File, args []string) (int, error) {
+	var iconfig *initConfig
+	if err := readSync(pipe, procConfig, &iconfig); err != nil {
+		pipe.Close()
+		return -1, err
+	}
+	config := iconfig.Config
+
+	var (
+		cmd     *exec.Cmd
+		started bool
+	)
+
+	createCommand := func(container *libcontainer.Config, console, dataPath, init string, child *os.File, args []string) *exec.Cmd {
+		cmd = DefaultCreateCommand(container, console, dataPath, init, child, args)
+		return cmd
+	}
+
+	exitCode, err := Exec(config, os.Stdin, os.Stdout, os.Stderr, "", dataPath, args, createCommand, func() {
+		started = true
+		writeSync(pipe, procStarted, cmd.Process.Pid)
+		pipe.Close()
+	})
+	if err != nil && !started {
+		writeSync(pipe, procError, newInitError("", err))
+		pipe.Close()
+	}
+	return exitCode, err
+}
+
+// startDetached starts the supervisor of the container's init process.  The
+// supervisor is started in a new session so that it does not receive the signals
+// sent to the caller's process group and keeps running after the caller exits.
+func (c *linuxContainer) startDetached(config *libcontainer.ProcessConfig, container *libcontainer.Config) (libcontainer.Process, libcontainer.Error) {
+	if config.Tty {
+		return nil, libcontainer.NewGenericError(fmt.Errorf("detached processes cannot have a tty"), libcontainer.ConfigInvalid)
+	}
+
+	stdio := []io.Closer{config.Stdin, config.Stdout, config.Stderr}
+	files := make([]*os.File, len(stdio))
+	for i, s := range stdio {
+		if s == nil {
+			continue
+		}
+		f, ok := s.(*os.File)
+		if !ok {
+			return nil, libcontainer.NewGenericError(fmt.Errorf("stdio of detached processes must be files"), libcontainer.ConfigInvalid)
+		}
+		files[i] = f
+	}
+
+	parent, child, err := newInitPipe()
+	if err != nil {
+		return nil, libcontainer.NewSystemError(err)
+	}
+	defer parent.Close()
+
+	cmd := reexec.Command(c.initPath, "supervise", append([]string{"--"}, config.Args...)...)
+	cmd.Env = append(os.Environ(), "data_path="+c.root, "pipe=3")
+	cmd.ExtraFiles = []*os.File{child}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	// a nil file is connected to /dev/null
+	if files[0] != nil {
+		cmd.Stdin = files[0]
+	}
+	if files[1] != nil {
+		cmd.Stdout = files[1]
+	}
+	if files[2] != nil {
+		cmd.Stderr = files[2]
+	}
+
+	if err := cmd.Start(); err != nil {
+		child.Close()
+		return nil, libcontainer.NewSystemError(err)
+	}
+	child.Close()
+	// the supervisor has its own copies of the files
+	closeStdio(config)
+
+	terminate := func(err libcontainer.Error) (libcontainer.Process, libcontainer.Error) {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	if err := writeSync(parent, procConfig, initConfig{Config: container}); err != nil {
+		return terminate(libcontainer.NewSystemError(err))
+	}
+
+	var pid int
+	if err := readSync(parent, procStarted, &pid); err != nil {
+		if ierr, ok := err.(initError); ok {
+			cmd.Wait()
+			return nil, ierr.toError()
+		}
+		return terminate(libcontainer.NewSystemErrorWithCause(err, "read supervisor status"))
+	}
+
+	process := &detachedProcess{
+		pid:  pid,
+		done: make(chan struct{}),
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			if _, ok := err.(*exec.ExitError); !ok {
+				process.err = libcontainer.NewSystemError(err)
+			}
+		}
+		if process.err == nil {
+			status, err := libcontainer.GetExitStatus(c.root)
+			if err != nil {
+				process.err = libcontainer.NewSystemErrorWithCause(err, "read exit status")
+			}
+			process.status = status
+		}
+		close(process.done)
+	}()
+
+	return process, nil
+}
+
+// detachedProcess is the init process of a detached container.  It is a child
+// of the supervisor, its exit status is read from the container's directory
+// once the supervisor exits.
+type detachedProcess struct {
+	pid    int
+	done   chan struct{}
+	status *libcontainer.ExitStatus
+	err    libcontainer.Error
+}
+
+func (p *detachedProcess) Pid() int {
+	return p.pid
+}
+
+func (p *detachedProcess) Wait() (*libcontainer.ExitStatus, libcontainer.Error) {
+	<-p.done
+	return p.status, p.err
+}
+
+func (p *detachedProcess) Signal(sig os.Signal) libcontainer.Error {
+	select {
+	case <-p.done:
+		return libcontainer.NewGenericError(fmt.Errorf("process %d already finished", p.pid), libcontainer.SystemError)
+	default:
+	}
+
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return libcontainer.NewGenericError(fmt.Errorf("unsupported signal %s", sig), libcontainer.SystemError)
+	}
+	if err := syscall.Kill(p.pid, s); err != nil {
+		return libcontainer.NewSystemError(err)
+	}
+	return nil
+}
+
+func (p *detachedProcess) Stdin() io.WriteCloser {
+	return nil
+}
+
+func (p *detachedProcess) Stdout() io.ReadCloser {
+	return nil
+}
+
+func (p *detachedProcess) Stderr() io.ReadCloser {
+	return nil
+}
+
+func (p *detachedProcess) Terminal() *os.File {
+	return nil
+}
@@ -0,0 +1,202 @@
+// +build linux
+
+package namespaces
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"syscall"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/network"
+)
+
+// syncType is the type of a message sent over the pipe between a process and the
+// init, nsenter or supervisor process that it started.
+type syncType string
+
+const (
+	// procConfig carries the initConfig of the process being started.
+	procConfig syncType = "config"
+
+	// procReady is sent by the container's init once the container is setup.  The
+	// init waits for procRun before it executes the user's process.
+	procReady syncType = "ready"
+
+	// procRun is sent to the container's init once the prestart hooks ran.
+	procRun syncType = "run"
+
+	// procStarted carries the pid of the init process started by the supervisor of
+	// a detached container.
+	procStarted syncType = "started"
+
+	// procError carries the initError of a process that failed at any stage.
+	procError syncType = "error"
+)
+
+// maxSyncSize is the largest message accepted from the pipe.
+const maxSyncSize = 1 << 24
+
+// syncMessage is a message of the sync protocol.  Each message is written as a
+// single frame made of its JSON encoding preceded by its length as a big endian
+// uint32.  The payload is decoded according to the message's type.
+type syncMessage struct {
+	Type    syncType        `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// initConfig is sent over the sync pipe to the container's init process.  The
+// config may differ from the container.json in the data path when the process
+// overrides the user, environment or working directory of the container.
+type initConfig struct {
+	Config       *libcontainer.Config  `json:"config,omitempty"`
+	NetworkState *network.NetworkState `json:"network_state,omitempty"`
+}
+
+// initError is sent over the sync pipe to the parent when the container's init
+// fails so that the stage, code and errno of the failure are preserved.
+type initError struct {
+	Stage   string                 `json:"stage,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Code    libcontainer.ErrorCode `json:"code"`
+	Errno   syscall.Errno          `json:"errno,omitempty"`
+}
+
+func newInitError(stage string, err error) initError {
+	lerr := libcontainer.NewSystemError(err)
+	return initError{
+		Stage:   stage,
+		Message: lerr.Error(),
+		Code:    lerr.Code(),
+		Errno:   lerr.Errno(),
+	}
+}
+
+func (i initError) Error() string {
+	if i.Stage == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", i.Stage, i.Message)
+}
+
+// toError returns the libcontainer.Error for the failure reported by the child.
+func (i initError) toError() libcontainer.Error {
+	return libcontainer.NewErrnoError(i.Error(), i.Code, i.Errno)
+}
+
+// writeSync writes a message of type t with v as its payload.  A nil v sends a
+// message without payload.
+func writeSync(w io.Writer, t syncType, v interface{}) error {
+	msg := syncMessage{Type: t}
+	if v != nil {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		msg.Payload = payload
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxSyncSize {
+		return fmt.Errorf("%s message of %d bytes exceeds the maximum size of %d bytes", t, len(data), maxSyncSize)
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+// readSyncMessage reads the next message from r.  io.EOF is returned if the pipe
+// was closed between two messages.
+func readSyncMessage(r io.Reader) (*syncMessage, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxSyncSize {
+		return nil, fmt.Errorf("sync message of %d bytes exceeds the maximum size of %d bytes", size, maxSyncSize)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	var msg *syncMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readSync reads the next message, which has to be of type t, and decodes its
+// payload into v.  The initError of a procError message is returned as the error.
+func readSync(r io.Reader, t syncType, v interface{}) error {
+	msg, err := readSyncMessage(r)
+	if err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("sync pipe closed while waiting for the %s message", t)
+		}
+		return err
+	}
+	if err := msg.err(); err != nil {
+		return err
+	}
+	if msg.Type != t {
+		return fmt.Errorf("unexpected %s message while waiting for the %s message", msg.Type, t)
+	}
+	if v == nil || len(msg.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(msg.Payload, v)
+}
+
+// waitSyncClose waits for the other end of the pipe to be closed, which happens
+// once the process executed the user's process as the pipe is close-on-exec.  The
+// initError of a procError message is returned as the error.
+func waitSyncClose(r io.Reader) error {
+	msg, err := readSyncMessage(r)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if err := msg.err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("unexpected %s message while waiting for the process to execute", msg.Type)
+}
+
+// err returns the initError of a procError message.
+func (m *syncMessage) err() error {
+	if m.Type != procError {
+		return nil
+	}
+	var ierr initError
+	if err := json.Unmarshal(m.Payload, &ierr); err != nil {
+		return err
+	}
+	return ierr
+}
+
+// syncError returns the libcontainer.Error of an initError read from the pipe, any
+// other error is returned as is.
+func syncError(err error) error {
+	if ierr, ok := err.(initError); ok {
+		return ierr.toError()
+	}
+	return err
+}
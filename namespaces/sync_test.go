@@ -0,0 +1,104 @@
+// +build linux
+
+package namespaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+func TestSyncRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSync(&buf, procConfig, initConfig{Config: &libcontainer.Config{Hostname: "test"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSync(&buf, procReady, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var iconfig *initConfig
+	if err := readSync(&buf, procConfig, &iconfig); err != nil {
+		t.Fatal(err)
+	}
+	if iconfig.Config == nil || iconfig.Config.Hostname != "test" {
+		t.Fatalf("expected the config to be received but received %+v", iconfig)
+	}
+	if err := readSync(&buf, procReady, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitSyncClose(&buf); err != nil {
+		t.Fatalf("expected the closed pipe to be reported as a success but received %s", err)
+	}
+}
+
+func TestSyncError(t *testing.T) {
+	var buf bytes.Buffer
+	cause := libcontainer.NewErrnoError("mount failed", libcontainer.SystemError, syscall.EPERM)
+	if err := writeSync(&buf, procError, newInitError("mounts", cause)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := syncError(readSync(&buf, procReady, nil))
+	lerr, ok := err.(libcontainer.Error)
+	if !ok {
+		t.Fatalf("expected a libcontainer.Error but received %v", err)
+	}
+	if lerr.Errno() != syscall.EPERM {
+		t.Fatalf("expected errno %d but received %d", syscall.EPERM, lerr.Errno())
+	}
+	if !strings.HasPrefix(lerr.Error(), "mounts: ") {
+		t.Fatalf("expected the error to name the stage but received %q", lerr.Error())
+	}
+}
+
+func TestReportSetnsError(t *testing.T) {
+	parent, child, err := newInitPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parent.Close()
+
+	if err := ReportSetnsError(child, syscall.ENOENT); err != nil {
+		t.Fatal(err)
+	}
+
+	err = syncError(waitSyncClose(parent))
+	lerr, ok := err.(libcontainer.Error)
+	if !ok {
+		t.Fatalf("expected a libcontainer.Error but received %v", err)
+	}
+	if lerr.Errno() != syscall.ENOENT {
+		t.Fatalf("expected errno %d but received %d", syscall.ENOENT, lerr.Errno())
+	}
+}
+
+func TestSyncUnexpected(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSync(&buf, procRun, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := readSync(&buf, procReady, nil); err == nil {
+		t.Fatal("expected an error reading an unexpected message")
+	}
+	if err := readSync(&buf, procReady, nil); err == nil {
+		t.Fatal("expected an error reading from a closed pipe")
+	}
+
+	buf.Reset()
+	binary.Write(&buf, binary.BigEndian, uint32(maxSyncSize+1))
+	if err := readSync(&buf, procReady, nil); err == nil {
+		t.Fatal("expected an error reading an oversized message")
+	}
+
+	buf.Reset()
+	fmt.Fprint(&buf, "\x00\x00\x00\x10{")
+	if err := waitSyncClose(&buf); err == nil {
+		t.Fatal("expected an error reading a truncated message")
+	}
+}
@@ -0,0 +1,119 @@
+// +build linux
+
+package namespaces
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/docker/libcontainer"
+)
+
+// setupUserNamespace configures the command to write the uid and gid mappings of
+// the container's new user namespace.  The mappings are written by the parent while
+// the child waits before executing the init, the child then switches to the
+// container's root so that the init keeps its capabilities in the namespace.
+func setupUserNamespace(command *exec.Cmd, container *libcontainer.Config) {
+	if GetNamespaceFlags(container.Namespaces, true)&syscall.CLONE_NEWUSER == 0 {
+		return
+	}
+
+	for _, m := range container.UidMappings {
+		command.SysProcAttr.UidMappings = append(command.SysProcAttr.UidMappings, syscall.SysProcIDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+	for _, m := range container.GidMappings {
+		command.SysProcAttr.GidMappings = append(command.SysProcAttr.GidMappings, syscall.SysProcIDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+
+	// an unprivileged parent can only write the gid map once setgroups is denied
+	// in the namespace, the container's processes then keep their groups
+	allowSetgroups := os.Geteuid() == 0
+	command.SysProcAttr.GidMappingsEnableSetgroups = allowSetgroups
+	command.SysProcAttr.Credential = &syscall.Credential{
+		Uid:         0,
+		Gid:         0,
+		NoSetGroups: !allowSetgroups,
+	}
+}
+
+// checkPrivileges checks that the current user can start a rootless container, only
+// the user's own ids can be mapped without root.  The privileges needed for other
+// containers are checked by the kernel.
+func checkPrivileges(container *libcontainer.Config) error {
+	if !container.Rootless || os.Geteuid() == 0 {
+		return nil
+	}
+
+	for _, m := range []struct {
+		kind     string
+		mappings []libcontainer.IDMap
+		id       int
+	}{
+		{"uid", container.UidMappings, os.Geteuid()},
+		{"gid", container.GidMappings, os.Getegid()},
+	} {
+		for _, mapping := range m.mappings {
+			if mapping.HostID != m.id {
+				return fmt.Errorf("mapping the %s %d other than the current user's %s %d requires root", m.kind, mapping.HostID, m.kind, m.id)
+			}
+		}
+	}
+	return nil
+}
+
+// setgroupsDenied reports whether setgroups is denied in the user namespace of
+// the current process.
+func setgroupsDenied() bool {
+	data, err := ioutil.ReadFile("/proc/self/setgroups")
+	if err != nil {
+		// kernels without the setgroups file allow it
+		return false
+	}
+	return strings.TrimSpace(string(data)) == "deny"
+}
+
+// chownUserNamespace gives the container's root on the host the ownership of the
+// container's console and cgroups so that the init can use them once it runs as
+// the container's root.
+func chownUserNamespace(container *libcontainer.Config, console string, cgroupPaths map[string]string) error {
+	if GetNamespaceFlags(container.Namespaces, true)&syscall.CLONE_NEWUSER == 0 {
+		return nil
+	}
+
+	uid, err := container.HostUID()
+	if err != nil {
+		return err
+	}
+	gid, err := container.HostGID()
+	if err != nil {
+		return err
+	}
+
+	if console != "" {
+		if err := os.Chown(console, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range cgroupPaths {
+		for _, p := range []string{path, filepath.Join(path, "cgroup.procs"), filepath.Join(path, "tasks")} {
+			if err := os.Chown(p, uid, gid); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// +build linux
+
+package namespaces
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/libcontainer"
+)
+
+func TestCheckPrivileges(t *testing.T) {
+	container := &libcontainer.Config{
+		Rootless:    true,
+		UidMappings: []libcontainer.IDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}},
+		GidMappings: []libcontainer.IDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}},
+	}
+	if err := checkPrivileges(container); err != nil {
+		t.Fatalf("expected the current user's ids to be mapped but received %s", err)
+	}
+
+	container.GidMappings[0].HostID = os.Getegid() + 1
+	err := checkPrivileges(container)
+	if os.Geteuid() == 0 && err != nil {
+		t.Fatalf("expected root to map any gid but received %s", err)
+	}
+	if os.Geteuid() != 0 && err == nil {
+		t.Fatal("expected an error mapping another user's gid")
+	}
+
+	// the privileges for containers that are not rootless are checked by the kernel
+	container.Rootless = false
+	if err := checkPrivileges(container); err != nil {
+		t.Fatalf("expected a container that is not rootless to be left to the kernel but received %s", err)
+	}
+}
@@ -7,23 +7,17 @@ import (
 	"syscall"
 
 	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/system"
 )
 
-type initError struct {
-	Message string `json:"message,omitempty"`
-}
-
-func (i initError) Error() string {
-	return i.Message
-}
-
 var namespaceInfo = map[libcontainer.NamespaceType]int{
-	libcontainer.NEWNET:  syscall.CLONE_NEWNET,
-	libcontainer.NEWNS:   syscall.CLONE_NEWNS,
-	libcontainer.NEWUSER: syscall.CLONE_NEWUSER,
-	libcontainer.NEWIPC:  syscall.CLONE_NEWIPC,
-	libcontainer.NEWUTS:  syscall.CLONE_NEWUTS,
-	libcontainer.NEWPID:  syscall.CLONE_NEWPID,
+	libcontainer.NEWNET:    syscall.CLONE_NEWNET,
+	libcontainer.NEWNS:     syscall.CLONE_NEWNS,
+	libcontainer.NEWUSER:   syscall.CLONE_NEWUSER,
+	libcontainer.NEWIPC:    syscall.CLONE_NEWIPC,
+	libcontainer.NEWUTS:    syscall.CLONE_NEWUTS,
+	libcontainer.NEWPID:    syscall.CLONE_NEWPID,
+	libcontainer.NEWCGROUP: system.CLONE_NEWCGROUP,
 }
 
 // New returns a newly initialized Pipe for communication between processes
@@ -15,6 +15,7 @@ import (
 	"github.com/docker/libcontainer"
 	consolepkg "github.com/docker/libcontainer/console"
 	"github.com/docker/libcontainer/namespaces"
+	"github.com/docker/libcontainer/reexec"
 )
 
 var execCommand = cli.Command{
@@ -48,15 +49,23 @@ func execAction(context *cli.Context) {
 		log.Fatal(err)
 	}
 
+	// the lock is held until the container's state is written so that only one
+	// init process is started for the data path
+	lock, err := namespaces.Lock(dataPath)
+	if err != nil {
+		log.Fatalf("unable to lock %s: %s", dataPath, err)
+	}
+
 	state, err := libcontainer.GetState(dataPath)
 	if err != nil && !os.IsNotExist(err) {
 		log.Fatalf("unable to read state.json: %s", err)
 	}
 
 	if state != nil {
+		lock.Unlock()
 		exitCode, err = startInExistingContainer(container, state, context.String("func"), context)
 	} else {
-		exitCode, err = startContainer(container, dataPath, []string(context.Args()))
+		exitCode, err = startContainer(container, dataPath, []string(context.Args()), lock)
 	}
 
 	if err != nil {
@@ -120,14 +129,15 @@ func startInExistingContainer(config *libcontainer.Config, state *libcontainer.S
 		}()
 	}
 
-	return namespaces.ExecIn(config, state, context.Args(), os.Args[0], action, stdin, stdout, stderr, console, startCallback)
+	return namespaces.ExecIn(config, state, context.Args(), reexec.Self(), action, stdin, stdout, stderr, console, startCallback)
 }
 
 // startContainer starts the container. Returns the exit status or -1 and an
 // error.
 //
-// Signals sent to the current process will be forwarded to container.
-func startContainer(container *libcontainer.Config, dataPath string, args []string) (int, error) {
+// Signals sent to the current process will be forwarded to container.  The lock
+// on the data path is released once the container is started.
+func startContainer(container *libcontainer.Config, dataPath string, args []string, lock *namespaces.FileLock) (int, error) {
 	var (
 		cmd  *exec.Cmd
 		sigc = make(chan os.Signal, 10)
@@ -175,6 +185,8 @@ func startContainer(container *libcontainer.Config, dataPath string, args []stri
 	}
 
 	startCallback := func() {
+		lock.Unlock()
+
 		go func() {
 			resizeTty(master)
 
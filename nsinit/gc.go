@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/codegangsta/cli"
+	"github.com/docker/libcontainer/namespaces"
+)
+
+var gcCommand = cli.Command{
+	Name:   "gc",
+	Usage:  "destroy the containers under a root directory whose init process died without cleaning up",
+	Action: gcAction,
+}
+
+func gcAction(context *cli.Context) {
+	if len(context.Args()) != 1 {
+		log.Fatal("expected the root directory of the containers as the only argument")
+	}
+
+	factory, err := namespaces.New(context.Args().First(), "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	destroyed, lerr := factory.Reconcile()
+	for _, id := range destroyed {
+		fmt.Println(id)
+	}
+	if lerr != nil {
+		log.Fatal(lerr)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/namespaces"
+)
+
+var listCommand = cli.Command{
+	Name:   "list",
+	Usage:  "list the containers under a root directory",
+	Action: listAction,
+	Flags: []cli.Flag{
+		cli.BoolFlag{Name: "json", Usage: "print the containers as JSON"},
+		cli.StringSliceFlag{Name: "state", Value: &cli.StringSlice{}, Usage: "only list the containers in the state, may be repeated"},
+		cli.StringSliceFlag{Name: "label", Value: &cli.StringSlice{}, Usage: "only list the containers with the label, as key=value, may be repeated"},
+	},
+}
+
+func listAction(context *cli.Context) {
+	if len(context.Args()) != 1 {
+		log.Fatal("expected the root directory of the containers as the only argument")
+	}
+
+	filter := &libcontainer.ListFilter{
+		Labels: make(map[string]string),
+	}
+	for _, name := range context.StringSlice("state") {
+		state, err := libcontainer.ParseRunState(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		filter.States = append(filter.States, state)
+	}
+	for _, label := range context.StringSlice("label") {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			log.Fatalf("invalid label %q, expected key=value", label)
+		}
+		filter.Labels[parts[0]] = parts[1]
+	}
+
+	factory, err := namespaces.New(context.Args().First(), "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	containers, lerr := factory.List(filter)
+	if lerr != nil {
+		log.Fatal(lerr)
+	}
+
+	if context.Bool("json") {
+		data, err := json.MarshalIndent(containers, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s\n", data)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATE\tPID\tEXIT\tCREATED\tROOTFS\tLABELS")
+	for _, c := range containers {
+		exit := ""
+		if c.ExitStatus != nil {
+			exit = c.ExitStatus.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", c.ID, c.State, c.InitPid, exit, c.Created.Format(time.RFC3339), c.RootFs, formatLabels(c.Labels))
+	}
+	w.Flush()
+}
+
+// formatLabels returns the labels as comma separated key=value pairs sorted by key.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
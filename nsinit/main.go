@@ -3,22 +3,18 @@ package main
 import (
 	"log"
 	"os"
-	"strings"
 
 	"github.com/codegangsta/cli"
+	"github.com/docker/libcontainer/reexec"
 )
 
 var (
-	logPath = os.Getenv("log")
-	argvs   = make(map[string]*rFunc)
+	logPath  = os.Getenv("log")
+	dataPath = os.Getenv("data_path")
+	argvs    = make(map[string]*rFunc)
 )
 
 func init() {
-	argvs["exec"] = &rFunc{
-		Usage:  "execute a process inside an existing container",
-		Action: nsenterExec,
-	}
-
 	argvs["mknod"] = &rFunc{
 		Usage:  "mknod a device inside an existing container",
 		Action: nsenterMknod,
@@ -28,15 +24,18 @@ func init() {
 		Usage:  "display the container's network interfaces",
 		Action: nsenterIp,
 	}
+
+	// the functions run by nsenter are registered under the name ExecIn
+	// uses as argv 0, the exec function is registered by namespaces
+	for name, f := range argvs {
+		reexec.Register("nsenter-"+name, f.run)
+	}
 }
 
 func main() {
 	// we need to check our argv 0 for any registred functions to run instead of the
 	// normal cli code path
-	f, exists := argvs[strings.TrimPrefix(os.Args[0], "nsenter-")]
-	if exists {
-		runFunc(f)
-
+	if reexec.Init() {
 		return
 	}
 
@@ -54,11 +53,13 @@ func main() {
 
 	app.Commands = []cli.Command{
 		execCommand,
-		initCommand,
 		statsCommand,
 		configCommand,
 		pauseCommand,
 		unpauseCommand,
+		gcCommand,
+		listCommand,
+		updateCommand,
 	}
 
 	if err := app.Run(os.Args); err != nil {
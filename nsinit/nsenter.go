@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"strconv"
@@ -12,35 +11,26 @@ import (
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/devices"
 	"github.com/docker/libcontainer/mount/nodes"
-	"github.com/docker/libcontainer/namespaces"
-	_ "github.com/docker/libcontainer/namespaces/nsenter"
 )
 
-// nsenterExec exec's a process inside an existing container
-func nsenterExec(config *libcontainer.Config, args []string) {
-	if err := namespaces.FinalizeSetns(config, args); err != nil {
-		log.Fatalf("failed to nsenter: %s", err)
-	}
-}
-
 // nsenterMknod runs mknod inside an existing container
 //
 // mknod <path> <type> <major> <minor>
-func nsenterMknod(config *libcontainer.Config, args []string) {
+func nsenterMknod(config *libcontainer.Config, args []string) error {
 	if len(args) != 4 {
-		log.Fatalf("expected mknod to have 4 arguments not %d", len(args))
+		return fmt.Errorf("expected mknod to have 4 arguments not %d", len(args))
 	}
 
 	t := rune(args[1][0])
 
 	major, err := strconv.Atoi(args[2])
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	minor, err := strconv.Atoi(args[3])
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	n := &devices.Device{
@@ -50,16 +40,14 @@ func nsenterMknod(config *libcontainer.Config, args []string) {
 		MinorNumber: int64(minor),
 	}
 
-	if err := nodes.CreateDeviceNode("/", n); err != nil {
-		log.Fatal(err)
-	}
+	return nodes.CreateDeviceNode("/", n)
 }
 
 // nsenterIp displays the network interfaces inside a container's net namespace
-func nsenterIp(config *libcontainer.Config, args []string) {
+func nsenterIp(config *libcontainer.Config, args []string) error {
 	interfaces, err := net.Interfaces()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0)
@@ -68,7 +56,7 @@ func nsenterIp(config *libcontainer.Config, args []string) {
 	for _, iface := range interfaces {
 		addrs, err := iface.Addrs()
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
 		o := []string{}
@@ -80,5 +68,5 @@ func nsenterIp(config *libcontainer.Config, args []string) {
 		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", iface.Name, iface.MTU, iface.HardwareAddr, iface.Flags, strings.Join(o, ","))
 	}
 
-	w.Flush()
+	return w.Flush()
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/docker/libcontainer/cgroups"
 	"github.com/docker/libcontainer/cgroups/fs"
 	"github.com/docker/libcontainer/cgroups/systemd"
+	"github.com/docker/libcontainer/namespaces"
 )
 
 var pauseCommand = cli.Command{
@@ -34,12 +35,18 @@ func unpauseAction(context *cli.Context) {
 }
 
 func toggle(state cgroups.FreezerState) error {
+	lock, err := namespaces.Lock(dataPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	container, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	if systemd.UseSystemd() {
+	if !container.Rootless && systemd.UseSystemd() {
 		err = systemd.Freeze(container.Cgroups, state)
 	} else {
 		err = fs.Freeze(container.Cgroups, state)
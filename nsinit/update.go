@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/namespaces"
+	"github.com/docker/libcontainer/utils"
+)
+
+var updateCommand = cli.Command{
+	Name:   "update",
+	Usage:  "update the cgroup limits of the container",
+	Action: updateAction,
+	Flags: []cli.Flag{
+		cli.IntFlag{Name: "memory", Usage: "memory limit (in bytes)"},
+		cli.IntFlag{Name: "memory-reservation", Usage: "memory soft limit (in bytes)"},
+		cli.IntFlag{Name: "memory-swap", Usage: "total memory usage (memory + swap), -1 to disable the swap limit"},
+		cli.IntFlag{Name: "cpu-shares", Usage: "cpu shares (relative weight)"},
+		cli.IntFlag{Name: "cpu-quota", Usage: "cpu hardcap limit (in usecs) in a cpu period"},
+		cli.IntFlag{Name: "cpu-period", Usage: "cpu period used for hardcapping (in usecs)"},
+		cli.StringFlag{Name: "cpuset-cpus", Usage: "cpus in which to allow execution (0-3, 0,1)"},
+		cli.StringFlag{Name: "cpuset-mems", Usage: "memory nodes in which to allow execution (0-3, 0,1)"},
+	},
+}
+
+func updateAction(context *cli.Context) {
+	if err := update(context); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func update(context *cli.Context) error {
+	lock, err := namespaces.Lock(dataPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	container, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if container.Cgroups == nil {
+		return fmt.Errorf("container has no cgroups configured")
+	}
+
+	c := *container.Cgroups
+	for name, value := range map[string]*int64{
+		"memory":             &c.Memory,
+		"memory-reservation": &c.MemoryReservation,
+		"memory-swap":        &c.MemorySwap,
+		"cpu-shares":         &c.CpuShares,
+		"cpu-quota":          &c.CpuQuota,
+		"cpu-period":         &c.CpuPeriod,
+	} {
+		if context.IsSet(name) {
+			*value = int64(context.Int(name))
+		}
+	}
+	if context.IsSet("cpuset-cpus") {
+		c.CpusetCpus = context.String("cpuset-cpus")
+	}
+	if context.IsSet("cpuset-mems") {
+		c.CpusetMems = context.String("cpuset-mems")
+	}
+
+	updated := *container
+	updated.Cgroups = &c
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+
+	// the limits of a stopped container are applied when it is started, a state left
+	// behind by an init that exited is reported as ContainerNotRunning
+	state, err := libcontainer.GetState(dataPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if state != nil {
+		if err := namespaces.UpdateCgroups(container, &c, state); err != nil {
+			return err
+		}
+	}
+
+	return utils.WriteJSON(filepath.Join(dataPath, "container.json"), &updated)
+}
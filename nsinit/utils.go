@@ -1,19 +1,19 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/codegangsta/cli"
 	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/namespaces"
 )
 
 // rFunc is a function registration for calling after an execin
 type rFunc struct {
 	Usage  string
-	Action func(*libcontainer.Config, []string)
+	Action func(*libcontainer.Config, []string) error
 }
 
 func loadConfig() (*libcontainer.Config, error) {
@@ -23,12 +23,7 @@ func loadConfig() (*libcontainer.Config, error) {
 	}
 	defer f.Close()
 
-	var container *libcontainer.Config
-	if err := json.NewDecoder(f).Decode(&container); err != nil {
-		return nil, err
-	}
-
-	return container, nil
+	return libcontainer.LoadConfig(f)
 }
 
 func openLog(name string) error {
@@ -56,16 +51,17 @@ func findUserArgs() []string {
 }
 
 // loadConfigFromFd loads a container's config from the sync pipe that is provided by
-// fd 3 when running a process
-func loadConfigFromFd() (*libcontainer.Config, error) {
+// fd 3 when running a process.  The pipe is returned so that failures are reported
+// over it.
+func loadConfigFromFd() (*libcontainer.Config, *os.File, error) {
 	pipe := os.NewFile(3, "pipe")
-	defer pipe.Close()
 
-	var config *libcontainer.Config
-	if err := json.NewDecoder(pipe).Decode(&config); err != nil {
-		return nil, err
+	config, err := namespaces.ReadSetnsConfig(pipe)
+	if err != nil {
+		pipe.Close()
+		return nil, nil, err
 	}
-	return config, nil
+	return config, pipe, nil
 }
 
 func preload(context *cli.Context) error {
@@ -78,13 +74,19 @@ func preload(context *cli.Context) error {
 	return nil
 }
 
-func runFunc(f *rFunc) {
+// run loads the config sent by ExecIn and runs the function with it.  ExecIn waits
+// until the function returned, its failure is sent back over the pipe.
+func (f *rFunc) run() {
 	userArgs := findUserArgs()
 
-	config, err := loadConfigFromFd()
+	config, pipe, err := loadConfigFromFd()
 	if err != nil {
 		log.Fatalf("unable to receive config from sync pipe: %s", err)
 	}
 
-	f.Action(config, userArgs)
+	if err := f.Action(config, userArgs); err != nil {
+		namespaces.ReportSetnsError(pipe, err)
+		log.Fatal(err)
+	}
+	pipe.Close()
 }
@@ -1,21 +1,45 @@
 package libcontainer
 
-import "io"
+import (
+	"io"
+	"os"
+)
 
 // Configuration for a process to be run inside a container.
 type ProcessConfig struct {
 	// The command to be run followed by any arguments.
 	Args []string
 
-	// Map of environment variables to their values.
+	// Map of environment variables to their values.  If nil, the environment from the
+	// container's config is used.
 	Env []string
 
+	// User overrides the user the process is executed as, if empty the user from the
+	// container's config is used.
+	User string
+
+	// Cwd overrides the working directory of the process inside the container's rootfs,
+	// if empty the working directory from the container's config is used.
+	Cwd string
+
+	// Detach starts the container's init process under a supervisor process so that the
+	// container keeps running after the caller exits.  The supervisor records the exit status
+	// of the init process in the container's directory.  Only the init process can be
+	// detached, Stdin, Stdout and Stderr must be nil or *os.File values, e.g. files or FIFOs,
+	// and Tty is not supported.
+	Detach bool
+
+	// Tty allocates a pseudo terminal for the process.  The master side of the terminal
+	// is returned by the Process' Terminal method and Stdin, Stdout and Stderr are ignored.
+	Tty bool
+
 	// Stdin is a pointer to a reader which provides the standard input stream.
 	// Stdout is a pointer to a writer which receives the standard output stream.
 	// Stderr is a pointer to a writer which receives the standard error stream.
 	//
-	// If a reader or writer is nil, the input stream is assumed to be empty and the output is
-	// discarded.
+	// If a reader or writer is nil, a pipe is created for the stream and its other end
+	// is returned by the Process' Stdin, Stdout or Stderr method.  The caller is
+	// responsible for closing the pipes.
 	//
 	// The readers and writers, if supplied, are closed when the process terminates. Their Close
 	// methods should be idempotent.
@@ -25,3 +49,38 @@ type ProcessConfig struct {
 	Stdout io.WriteCloser
 	Stderr io.WriteCloser
 }
+
+// A process started inside a container by Container.Start.
+type Process interface {
+	// Returns the PID of the process in the caller process's namespace.
+	Pid() int
+
+	// Waits for the process to exit and returns its exit status.  Wait may be called
+	// multiple times, every call returns the same result.
+	//
+	// Errors:
+	// SystemError - System error.
+	Wait() (*ExitStatus, Error)
+
+	// Sends a signal to the process.
+	//
+	// Errors:
+	// SystemError - System error.
+	Signal(sig os.Signal) Error
+
+	// Returns the write end of the process' stdin pipe, nil if Stdin was provided in
+	// the ProcessConfig or the process has a terminal.
+	Stdin() io.WriteCloser
+
+	// Returns the read end of the process' stdout pipe, nil if Stdout was provided in
+	// the ProcessConfig or the process has a terminal.
+	Stdout() io.ReadCloser
+
+	// Returns the read end of the process' stderr pipe, nil if Stderr was provided in
+	// the ProcessConfig or the process has a terminal.
+	Stderr() io.ReadCloser
+
+	// Returns the master side of the process' pseudo terminal, nil if the process was
+	// started without Tty.  The caller is responsible for closing the terminal.
+	Terminal() *os.File
+}
@@ -0,0 +1,57 @@
+// Package reexec runs the initializers that a program registered under a name when
+// the program re-executes itself with that name as argv[0], for example to run the
+// init process of a container.
+package reexec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+var registeredInitializers = make(map[string]func())
+
+// Register adds an initializer that is run by Init when the program is executed
+// with name as argv[0].  It panics if an initializer is already registered under
+// name.
+func Register(name string, initializer func()) {
+	if _, exists := registeredInitializers[name]; exists {
+		panic(fmt.Sprintf("reexec initializer already registered under name %q", name))
+	}
+	registeredInitializers[name] = initializer
+}
+
+// Init runs the initializer registered under the program's argv[0] and returns
+// true if one was run.  It is called at the top of main so that the re-executed
+// program runs the initializer instead of its normal code path:
+//
+//	func main() {
+//		if reexec.Init() {
+//			return
+//		}
+//		...
+//	}
+func Init() bool {
+	initializer, exists := registeredInitializers[os.Args[0]]
+	if !exists {
+		return false
+	}
+	initializer()
+	return true
+}
+
+// Command returns the command executing the binary at path with name as argv[0],
+// followed by args, so that the initializer registered under name is run.  path is
+// looked up in PATH if it is a bare name.
+func Command(path, name string, args ...string) *exec.Cmd {
+	if filepath.Base(path) == path {
+		if lp, err := exec.LookPath(path); err == nil {
+			path = lp
+		}
+	}
+	return &exec.Cmd{
+		Path: path,
+		Args: append([]string{name}, args...),
+	}
+}
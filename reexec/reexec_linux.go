@@ -0,0 +1,10 @@
+// +build linux
+
+package reexec
+
+// Self returns the path of the running program's binary.  /proc/self/exe refers to
+// the binary that is executing even if os.Args[0] is a name found in PATH or was
+// rewritten by the program.
+func Self() string {
+	return "/proc/self/exe"
+}
@@ -0,0 +1,50 @@
+package reexec
+
+import (
+	"os"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	var ran bool
+	Register("reexec-test", func() {
+		ran = true
+	})
+	defer delete(registeredInitializers, "reexec-test")
+
+	if Init() {
+		t.Fatal("expected no initializer to run for the test binary")
+	}
+
+	argv0 := os.Args[0]
+	os.Args[0] = "reexec-test"
+	defer func() {
+		os.Args[0] = argv0
+	}()
+
+	if !Init() || !ran {
+		t.Fatal("expected the initializer registered under argv[0] to run")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	Register("reexec-twice", func() {})
+	defer delete(registeredInitializers, "reexec-twice")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected registering a name twice to panic")
+		}
+	}()
+	Register("reexec-twice", func() {})
+}
+
+func TestCommand(t *testing.T) {
+	cmd := Command(Self(), "reexec-test", "--", "true")
+	if cmd.Path != Self() {
+		t.Fatalf("expected the command to execute %q but received %q", Self(), cmd.Path)
+	}
+	if len(cmd.Args) != 3 || cmd.Args[0] != "reexec-test" || cmd.Args[2] != "true" {
+		t.Fatalf("expected the name as argv[0] but received %v", cmd.Args)
+	}
+}
@@ -0,0 +1,24 @@
+// +build !linux
+
+package reexec
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Self returns the path of the running program's binary, it is looked up from
+// os.Args[0] as the platform has no /proc/self/exe.
+func Self() string {
+	name := os.Args[0]
+	if filepath.Base(name) == name {
+		if lp, err := exec.LookPath(name); err == nil {
+			return lp
+		}
+	}
+	if absName, err := filepath.Abs(name); err == nil {
+		return absName
+	}
+	return name
+}
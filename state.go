@@ -1,15 +1,19 @@
 package libcontainer
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/docker/libcontainer/network"
+	"github.com/docker/libcontainer/utils"
 )
 
 // State represents a running container's state
 type State struct {
+	// Version of the state.json format the state was written with.
+	Version int `json:"version,omitempty"`
+
 	// InitPid is the init process id in the parent namespace
 	InitPid int `json:"init_pid,omitempty"`
 
@@ -21,6 +25,14 @@ type State struct {
 
 	// Path to all the cgroups setup for a container. Key is cgroup subsystem name.
 	CgroupPaths map[string]string `json:"cgroup_paths,omitempty"`
+
+	// NamespacePaths maps the types of the container's namespaces to their files persisted in the
+	// container's directory.
+	NamespacePaths map[NamespaceType]string `json:"namespace_paths,omitempty"`
+
+	// JoinedNamespaces maps the types of the namespaces joined from other containers to the
+	// ids of the containers.
+	JoinedNamespaces map[NamespaceType]string `json:"joined_namespaces,omitempty"`
 }
 
 // The running state of the container.
@@ -41,21 +53,62 @@ const (
 
 	// The container does not exist.
 	Destroyed
+
+	// The container exists, but its init process is not running.
+	Stopped
 )
 
-// SaveState writes the container's runtime state to a state.json file
-// in the specified path
-func SaveState(basePath string, state *State) error {
-	f, err := os.Create(filepath.Join(basePath, stateFile))
+var runStateNames = map[RunState]string{
+	Running:   "running",
+	Pausing:   "pausing",
+	Paused:    "paused",
+	Destroyed: "destroyed",
+	Stopped:   "stopped",
+}
+
+func (s RunState) String() string {
+	if name, ok := runStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("RunState(%d)", int(s))
+}
+
+// ParseRunState returns the RunState with the name returned by String.
+func ParseRunState(name string) (RunState, error) {
+	for s, n := range runStateNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown run state %q", name)
+}
+
+// MarshalText encodes the run state as its name so that it is readable in JSON.
+func (s RunState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *RunState) UnmarshalText(text []byte) error {
+	parsed, err := ParseRunState(string(text))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	*s = parsed
+	return nil
+}
 
-	return json.NewEncoder(f).Encode(state)
+// SaveState writes the container's runtime state to a state.json file
+// in the specified path
+func SaveState(basePath string, state *State) error {
+	// the version is set on a copy so that the caller's state is left untouched
+	copied := *state
+	copied.Version = StateVersion
+
+	return utils.WriteJSON(filepath.Join(basePath, stateFile), &copied)
 }
 
-// GetState reads the state.json file for a running container
+// GetState reads the state.json file for a running container.  States written
+// before the format was versioned are read as the current version.
 func GetState(basePath string) (*State, error) {
 	f, err := os.Open(filepath.Join(basePath, stateFile))
 	if err != nil {
@@ -63,12 +116,7 @@ func GetState(basePath string) (*State, error) {
 	}
 	defer f.Close()
 
-	var state *State
-	if err := json.NewDecoder(f).Decode(&state); err != nil {
-		return nil, err
-	}
-
-	return state, nil
+	return LoadState(f)
 }
 
 // DeleteState deletes the state.json file
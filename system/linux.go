@@ -3,11 +3,16 @@
 package system
 
 import (
+	"io/ioutil"
 	"os/exec"
+	"strings"
 	"syscall"
 	"unsafe"
 )
 
+// CLONE_NEWCGROUP creates a new cgroup namespace, it is not defined by the syscall package.
+const CLONE_NEWCGROUP = 0x02000000
+
 func Execv(cmd string, args []string, env []string) error {
 	name, err := exec.LookPath(cmd)
 	if err != nil {
@@ -58,3 +63,15 @@ func Setctty() error {
 	}
 	return nil
 }
+
+// RunningInUserNS reports whether the current process runs in a user namespace
+// other than the initial one, whose uid map covers every id.
+func RunningInUserNS() bool {
+	data, err := ioutil.ReadFile("/proc/self/uid_map")
+	if err != nil {
+		// kernels without user namespaces do not have the uid map
+		return false
+	}
+	fields := strings.Fields(string(data))
+	return len(fields) != 3 || fields[0] != "0" || fields[1] != "0" || fields[2] != "4294967295"
+}
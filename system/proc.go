@@ -1,6 +1,8 @@
 package system
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strconv"
@@ -25,3 +27,24 @@ func GetProcessStartTime(pid int) (string, error) {
 	// (divide by sysconf(_SC_CLK_TCK)).
 	return parts[22-1], nil // starts at 1
 }
+
+// GetProcessState returns the state of the process from /proc, for example "R"
+// for a running process or "Z" for a zombie that was not yet reaped
+func GetProcessState(pid int) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return "", err
+	}
+
+	// the state follows the command name which is in parentheses and may
+	// contain spaces
+	i := bytes.LastIndex(data, []byte(")"))
+	if i == -1 {
+		return "", fmt.Errorf("invalid stat format for pid %d", pid)
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("invalid stat format for pid %d", pid)
+	}
+	return fields[0], nil
+}
@@ -7,10 +7,12 @@ import (
 	"os"
 )
 
-// Unix-specific path to the passwd and group formatted files.
+// Unix-specific path to the passwd, group and subid formatted files.
 const (
 	unixPasswdPath = "/etc/passwd"
 	unixGroupPath  = "/etc/group"
+	unixSubuidPath = "/etc/subuid"
+	unixSubgidPath = "/etc/subgid"
 )
 
 func GetPasswdPath() (string, error) {
@@ -28,3 +30,11 @@ func GetGroupPath() (string, error) {
 func GetGroup() (io.ReadCloser, error) {
 	return os.Open(unixGroupPath)
 }
+
+func GetSubuidPath() (string, error) {
+	return unixSubuidPath, nil
+}
+
+func GetSubgidPath() (string, error) {
+	return unixSubgidPath, nil
+}
@@ -19,3 +19,11 @@ func GetGroupPath() (string, error) {
 func GetGroup() (io.ReadCloser, error) {
 	return nil, ErrUnsupported
 }
+
+func GetSubuidPath() (string, error) {
+	return "", ErrUnsupported
+}
+
+func GetSubgidPath() (string, error) {
+	return "", ErrUnsupported
+}
@@ -0,0 +1,123 @@
+package user
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubID is a range of subordinate ids allotted to a user in /etc/subuid or
+// /etc/subgid.
+type SubID struct {
+	Name  string
+	SubID int
+	Count int
+}
+
+func ParseSubIDFile(path string) ([]SubID, error) {
+	subid, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer subid.Close()
+	return ParseSubID(subid)
+}
+
+func ParseSubID(subid io.Reader) ([]SubID, error) {
+	return ParseSubIDFilter(subid, nil)
+}
+
+func ParseSubIDFileFilter(path string, filter func(SubID) bool) ([]SubID, error) {
+	subid, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer subid.Close()
+	return ParseSubIDFilter(subid, filter)
+}
+
+func ParseSubIDFilter(r io.Reader, filter func(SubID) bool) ([]SubID, error) {
+	if r == nil {
+		return nil, fmt.Errorf("nil source for subid-formatted data")
+	}
+
+	var (
+		s   = bufio.NewScanner(r)
+		out = []SubID{}
+	)
+
+	for s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// see: man 5 subuid
+		//  login_name:first_id:count
+		// Name:SubID:Count
+		//  alice:100000:65536
+		p := SubID{}
+		parseLine(
+			text,
+			&p.Name, &p.SubID, &p.Count,
+		)
+
+		if filter == nil || filter(p) {
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
+
+// GetSubIDsForUser returns the ranges of the subid-formatted file at path that
+// are allotted to u, by name or by uid.
+func GetSubIDsForUser(path string, u User) ([]SubID, error) {
+	return ParseSubIDFileFilter(path, func(s SubID) bool {
+		return s.Name == u.Name || s.Name == strconv.Itoa(u.Uid)
+	})
+}
+
+// ValidateSubIDMapping checks the mapping of count ids starting at id inside a user
+// namespace to the ids starting at parentID.  The parent ids must either be ownID,
+// the id of the user itself, or fall within the ranges allotted to the user.
+func ValidateSubIDMapping(id, parentID, count int, ranges []SubID, ownID int) error {
+	if count <= 0 {
+		return fmt.Errorf("invalid mapping of %d ids at %d", count, parentID)
+	}
+	if id < minId || parentID < minId || id+count-1 > maxId || parentID+count-1 > maxId {
+		return ErrRange
+	}
+
+	allotted := append([]SubID{{SubID: ownID, Count: 1}}, ranges...)
+	sort.Sort(bySubID(allotted))
+
+	// walk the allotted ranges, which may be adjacent, until the mapping is covered
+	start, end := parentID, parentID+count
+	for _, r := range allotted {
+		if r.SubID <= start && start < r.SubID+r.Count {
+			start = r.SubID + r.Count
+		}
+		if start >= end {
+			break
+		}
+	}
+	if start < end {
+		return fmt.Errorf("id %d of the mapping of %d ids at %d is not allotted to the user", start, count, parentID)
+	}
+	return nil
+}
+
+type bySubID []SubID
+
+func (s bySubID) Len() int           { return len(s) }
+func (s bySubID) Less(i, j int) bool { return s[i].SubID < s[j].SubID }
+func (s bySubID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -350,3 +350,53 @@ this is just some garbage data
 		}
 	}
 }
+
+func TestUserParseSubID(t *testing.T) {
+	subids, err := ParseSubIDFilter(strings.NewReader(`
+# comment
+alice:100000:65536
+1000:165536:1000
+bob:200000:65536
+`), func(s SubID) bool {
+		return s.Name == "alice" || s.Name == "1000"
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(subids) != 2 {
+		t.Fatalf("Expected 2 ranges, got %v", len(subids))
+	}
+	if subids[0].Name != "alice" || subids[0].SubID != 100000 || subids[0].Count != 65536 {
+		t.Fatalf("Expected subids[0] to be alice - 100000 - 65536, got %v - %v - %v", subids[0].Name, subids[0].SubID, subids[0].Count)
+	}
+	if subids[1].SubID != 165536 || subids[1].Count != 1000 {
+		t.Fatalf("Expected subids[1] to be 165536 - 1000, got %v - %v", subids[1].SubID, subids[1].Count)
+	}
+}
+
+func TestValidateSubIDMapping(t *testing.T) {
+	ranges := []SubID{{Name: "alice", SubID: 165536, Count: 1000}, {Name: "alice", SubID: 100000, Count: 65536}}
+
+	for _, m := range [][3]int{
+		{0, 1000, 1},
+		{1, 100000, 65536},
+		// adjacent ranges cover a single mapping
+		{1, 160000, 6000},
+	} {
+		if err := ValidateSubIDMapping(m[0], m[1], m[2], ranges, 1000); err != nil {
+			t.Errorf("Expected %v to be valid, got %v", m, err)
+		}
+	}
+
+	for _, m := range [][3]int{
+		{0, 0, 1},
+		{0, 999, 2},
+		{1, 100000, 70000},
+		{1, 100000, 0},
+		{-1, 1000, 1},
+	} {
+		if err := ValidateSubIDMapping(m[0], m[1], m[2], ranges, 1000); err == nil {
+			t.Errorf("Expected %v to be invalid", m)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package utils
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestGenerateName(t *testing.T) {
 	name, err := GenerateRandomName("veth", 5)
@@ -13,3 +18,68 @@ func TestGenerateName(t *testing.T) {
 		t.Fatalf("expected name to be %d chars but received %d", expected, len(name))
 	}
 }
+
+func TestWriteJSON(t *testing.T) {
+	root, err := ioutil.TempDir("", "libcontainer-utils")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "state.json")
+	for _, v := range []string{"first", "second"} {
+		if err := WriteJSON(path, map[string]string{"value": v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\"value\":\"second\"}\n" {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected the temporary files to be renamed but found %d files", len(files))
+	}
+}
+
+func TestWriteJSONMode(t *testing.T) {
+	root, err := ioutil.TempDir("", "libcontainer-utils")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "state.json")
+	if err := WriteJSON(path, "new"); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Fatalf("expected a new file to have mode 0644 but received %v", fi.Mode().Perm())
+	}
+
+	// the mode of an existing file is kept when it is replaced
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteJSON(path, "replaced"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Fatalf("expected the replaced file to keep mode 0640 but received %v", fi.Mode().Perm())
+	}
+}
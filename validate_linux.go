@@ -0,0 +1,594 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/devices"
+	"github.com/docker/libcontainer/mount"
+	"github.com/docker/libcontainer/network"
+	"github.com/docker/libcontainer/security/capabilities"
+)
+
+// FieldError describes a single problem found in a container's config.  Field
+// is the path to the offending value using the config's JSON field names,
+// for example networks[0].type.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is the list of every problem found in a config by Validate.
+type ValidationErrors []*FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("invalid config: %s", strings.Join(msgs, "; "))
+}
+
+var namespaceTypes = map[NamespaceType]bool{
+	NEWNET:    true,
+	NEWPID:    true,
+	NEWNS:     true,
+	NEWUTS:    true,
+	NEWIPC:    true,
+	NEWUSER:   true,
+	NEWCGROUP: true,
+}
+
+// Validate checks the config for values that are inconsistent with each other or
+// that cannot be applied on the host so that they are reported before any process
+// is started for the container.  All of the problems found are returned at once
+// as ValidationErrors.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.validateNamespaces(c)
+	v.validateUserNamespace(c)
+	v.validateRootless(c)
+	v.validateRootFs(c)
+	v.validateEnv(c)
+	v.validateCapabilities(c)
+	v.validateMountConfig(c)
+	v.validateCgroups(c)
+	v.validateNetworks(c)
+	v.validateRoutes(c)
+	v.validateHooks(c)
+	v.validateStopSignal(c)
+	v.validateSysctl(c)
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+type validator struct {
+	errors ValidationErrors
+}
+
+func (v *validator) add(field, format string, a ...interface{}) {
+	v.errors = append(v.errors, &FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (v *validator) validateNamespaces(c *Config) {
+	seen := make(map[NamespaceType]bool)
+	for i, ns := range c.Namespaces {
+		field := fmt.Sprintf("namespaces[%d]", i)
+		if !namespaceTypes[ns.Type] {
+			v.add(field+".type", "unknown namespace type %q", ns.Type)
+		}
+		if seen[ns.Type] {
+			v.add(field+".type", "namespace %s is specified more than once", ns.Type)
+		}
+		seen[ns.Type] = true
+		if ns.Path != "" && !filepath.IsAbs(ns.Path) {
+			v.add(field+".path", "path %q is not absolute", ns.Path)
+		}
+	}
+
+	if !c.Namespaces.Contains(NEWNET) {
+		if len(c.Networks) != 0 {
+			v.add("networks", "networks require the NEWNET namespace")
+		}
+		if len(c.Routes) != 0 {
+			v.add("routes", "routes require the NEWNET namespace")
+		}
+	}
+	if !c.Namespaces.Contains(NEWNS) {
+		if c.MountConfig != nil {
+			v.add("mount_config", "mount_config requires the NEWNS namespace")
+		}
+		if c.RestrictSys {
+			v.add("restrict_sys", "restrict_sys requires the NEWNS namespace")
+		}
+	}
+	if c.Hostname != "" && !c.Namespaces.Contains(NEWUTS) {
+		v.add("hostname", "hostname requires the NEWUTS namespace")
+	}
+	if c.BuiltinInit && !c.Namespaces.Contains(NEWPID) {
+		v.add("builtin_init", "builtin_init requires the NEWPID namespace")
+	}
+}
+
+func (v *validator) validateUserNamespace(c *Config) {
+	if !c.Namespaces.Contains(NEWUSER) {
+		if len(c.UidMappings) != 0 {
+			v.add("uid_mappings", "uid_mappings require the NEWUSER namespace")
+		}
+		if len(c.GidMappings) != 0 {
+			v.add("gid_mappings", "gid_mappings require the NEWUSER namespace")
+		}
+		return
+	}
+	// the mappings of a joined user namespace are already written
+	for _, ns := range c.Namespaces {
+		if ns.Type == NEWUSER && ns.Path != "" {
+			return
+		}
+	}
+
+	for _, m := range []struct {
+		field    string
+		mappings []IDMap
+	}{
+		{"uid_mappings", c.UidMappings},
+		{"gid_mappings", c.GidMappings},
+	} {
+		if len(m.mappings) == 0 {
+			v.add(m.field, "%s are required by the NEWUSER namespace", m.field)
+			continue
+		}
+		for i, id := range m.mappings {
+			field := fmt.Sprintf("%s[%d]", m.field, i)
+			if id.ContainerID < 0 || id.HostID < 0 {
+				v.add(field, "ids cannot be negative")
+			}
+			if id.Size <= 0 {
+				v.add(field+".size", "size %d must be positive", id.Size)
+			}
+			// the kernel rejects maps with overlapping ranges inside the container
+			for j, other := range m.mappings[:i] {
+				if id.ContainerID < other.ContainerID+other.Size && other.ContainerID < id.ContainerID+id.Size {
+					v.add(field, "container ids overlap with %s[%d]", m.field, j)
+				}
+			}
+		}
+		if _, err := hostID(m.mappings, 0); err != nil {
+			v.add(m.field, "the container's root is not mapped")
+		}
+	}
+}
+
+// validateRootless checks the settings that require root in a rootless config.  The
+// ids of the user starting the container are checked when it is started so that
+// the validation does not depend on the user running it.
+func (v *validator) validateRootless(c *Config) {
+	if !c.Rootless {
+		return
+	}
+
+	if !c.Namespaces.Contains(NEWUSER) {
+		v.add("rootless", "rootless containers require the NEWUSER namespace")
+	} else {
+		// an unprivileged user can only map its own ids
+		for _, m := range []struct {
+			field    string
+			mappings []IDMap
+		}{
+			{"uid_mappings", c.UidMappings},
+			{"gid_mappings", c.GidMappings},
+		} {
+			if len(m.mappings) > 1 || (len(m.mappings) == 1 && m.mappings[0].Size != 1) {
+				v.add(m.field, "mapping ids other than the current user's id requires root")
+			}
+		}
+	}
+
+	if c.Namespaces.Contains(NEWNS) {
+		// the system mounts are only permitted in namespaces owned by the user namespace
+		if !c.Namespaces.Contains(NEWPID) {
+			v.add("namespaces", "mounting /proc without the NEWPID namespace requires root")
+		}
+		if !c.Namespaces.Contains(NEWIPC) {
+			v.add("namespaces", "mounting /dev/mqueue without the NEWIPC namespace requires root")
+		}
+	}
+	if c.PersistNamespaces {
+		v.add("persist_namespaces", "bind mounting the namespace files requires root")
+	}
+	for i, n := range c.Networks {
+		if n.Type != "loopback" {
+			v.add(fmt.Sprintf("networks[%d].type", i), "%s networks require root, rootless containers only support loopback networks", n.Type)
+		}
+	}
+}
+
+func (v *validator) validateRootFs(c *Config) {
+	// an empty rootfs runs the container in the current working directory
+	if c.RootFs == "" {
+		return
+	}
+	fi, err := os.Stat(c.RootFs)
+	if err != nil {
+		v.add("root_fs", "%s", err)
+		return
+	}
+	if !fi.IsDir() {
+		v.add("root_fs", "%q is not a directory", c.RootFs)
+	}
+}
+
+func (v *validator) validateEnv(c *Config) {
+	for i, pair := range c.Env {
+		if !strings.Contains(pair, "=") {
+			v.add(fmt.Sprintf("environment[%d]", i), "%q is not in the form KEY=VALUE", pair)
+		}
+	}
+}
+
+func (v *validator) validateCapabilities(c *Config) {
+	for i, name := range c.Capabilities {
+		if capabilities.GetCapability(name) == nil {
+			v.add(fmt.Sprintf("capabilities[%d]", i), "unknown capability %q", name)
+		}
+	}
+}
+
+func (v *validator) validateMountConfig(c *Config) {
+	if c.MountConfig == nil {
+		return
+	}
+
+	for i, m := range c.MountConfig.Mounts {
+		field := fmt.Sprintf("mount_config.mounts[%d]", i)
+		switch m.Type {
+		case "bind":
+			if m.Source == "" {
+				v.add(field+".source", "source is required for bind mounts")
+			} else if _, err := os.Stat(m.Source); err != nil {
+				v.add(field+".source", "%s", err)
+			}
+		case "tmpfs":
+		case "cgroup":
+			if !c.Namespaces.Contains(NEWCGROUP) {
+				v.add(field+".type", "cgroup mounts require the NEWCGROUP namespace")
+			}
+		default:
+			v.add(field+".type", "unsupported mount type %q", m.Type)
+		}
+		if m.Destination == "" {
+			v.add(field+".destination", "destination is required")
+		}
+		if m.Propagation != "" {
+			flags, ok := mount.PropagationFlags[m.Propagation]
+			switch {
+			case !ok:
+				v.add(field+".propagation", "unknown propagation mode %q", m.Propagation)
+			case flags&(syscall.MS_SLAVE|syscall.MS_SHARED) != 0 && rootfsPrivate(c.MountConfig):
+				v.add(field+".propagation", "%s mounts require a slave or shared rootfs_propagation", m.Propagation)
+			}
+		}
+	}
+
+	if _, ok := mount.PropagationFlags[c.MountConfig.RootfsPropagation]; c.MountConfig.RootfsPropagation != "" && !ok {
+		v.add("mount_config.rootfs_propagation", "unknown propagation mode %q", c.MountConfig.RootfsPropagation)
+	}
+
+	for i, d := range c.MountConfig.DeviceNodes {
+		field := fmt.Sprintf("mount_config.device_nodes[%d]", i)
+		v.validateDevice(field, d, false)
+		if d.Path == "" {
+			v.add(field+".path", "path is required for device nodes")
+		}
+		if d.MajorNumber == devices.Wildcard || d.MinorNumber == devices.Wildcard {
+			v.add(field, "device nodes cannot use wildcard device numbers")
+		}
+	}
+}
+
+// rootfsPrivate reports whether the container's mounts are private, in which case
+// they do not receive the mounts made on the host.
+func rootfsPrivate(mc *MountConfig) bool {
+	switch mc.RootfsPropagation {
+	case "":
+		return !mc.NoPivotRoot
+	case "private", "rprivate":
+		return true
+	}
+	return false
+}
+
+// validateDevice checks the device's type, numbers and cgroup permissions.  The
+// 'a' type matching all devices is only valid in cgroup rules.
+func (v *validator) validateDevice(field string, d *devices.Device, cgroupRule bool) {
+	switch d.Type {
+	case 'c', 'b', 'p':
+	case 'a':
+		if !cgroupRule {
+			v.add(field+".type", "device type 'a' is only valid for cgroup rules")
+		}
+	default:
+		v.add(field+".type", "unknown device type %q", d.Type)
+	}
+
+	if d.Path != "" && !filepath.IsAbs(d.Path) {
+		v.add(field+".path", "path %q is not absolute", d.Path)
+	}
+	if d.MajorNumber < devices.Wildcard {
+		v.add(field+".major_number", "invalid device number %d", d.MajorNumber)
+	}
+	if d.MinorNumber < devices.Wildcard {
+		v.add(field+".minor_number", "invalid device number %d", d.MinorNumber)
+	}
+	for _, p := range d.CgroupPermissions {
+		if !strings.ContainsRune("rwm", p) {
+			v.add(field+".cgroup_permissions", "invalid permissions %q, only r, w and m are allowed", d.CgroupPermissions)
+			break
+		}
+	}
+}
+
+func (v *validator) validateCgroups(c *Config) {
+	cg := c.Cgroups
+	if cg == nil {
+		return
+	}
+
+	if cg.Memory < 0 {
+		v.add("cgroups.memory", "memory limit %d cannot be negative", cg.Memory)
+	}
+	if cg.MemoryReservation < 0 {
+		v.add("cgroups.memory_reservation", "memory reservation %d cannot be negative", cg.MemoryReservation)
+	}
+	if cg.MemorySwap < -1 {
+		v.add("cgroups.memory_swap", "memory swap %d must be -1 or greater", cg.MemorySwap)
+	}
+	if cg.MemorySwap > 0 && cg.Memory > 0 && cg.MemorySwap < cg.Memory {
+		v.add("cgroups.memory_swap", "memory swap %d must not be lower than the memory limit %d", cg.MemorySwap, cg.Memory)
+	}
+	if cg.CpuShares < 0 {
+		v.add("cgroups.cpu_shares", "cpu shares %d cannot be negative", cg.CpuShares)
+	}
+	if cg.CpuQuota < -1 {
+		v.add("cgroups.cpu_quota", "cpu quota %d must be -1 or greater", cg.CpuQuota)
+	}
+	if cg.CpuPeriod != 0 && (cg.CpuPeriod < 1000 || cg.CpuPeriod > 1000000) {
+		v.add("cgroups.cpu_period", "cpu period %d must be between 1000 and 1000000", cg.CpuPeriod)
+	}
+	if err := validateCpuList(cg.CpusetCpus); err != nil {
+		v.add("cgroups.cpuset_cpus", "%s", err)
+	}
+	if err := validateCpuList(cg.CpusetMems); err != nil {
+		v.add("cgroups.cpuset_mems", "%s", err)
+	}
+	switch cg.Freezer {
+	case cgroups.Undefined, cgroups.Frozen, cgroups.Thawed:
+	default:
+		v.add("cgroups.freezer", "unknown freezer state %q", cg.Freezer)
+	}
+
+	for i, d := range cg.AllowedDevices {
+		v.validateDevice(fmt.Sprintf("cgroups.allowed_devices[%d]", i), d, true)
+	}
+}
+
+// validateCpuList checks a list in the format used by the cpuset cgroup,
+// for example 0-3,6.
+func validateCpuList(list string) error {
+	if list == "" {
+		return nil
+	}
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		start, err := strconv.ParseUint(bounds[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid list %q", list)
+		}
+		if len(bounds) == 2 {
+			end, err := strconv.ParseUint(bounds[1], 10, 32)
+			if err != nil || end < start {
+				return fmt.Errorf("invalid list %q", list)
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateNetworks(c *Config) {
+	for i, n := range c.Networks {
+		field := fmt.Sprintf("networks[%d]", i)
+		if _, err := network.GetStrategy(n.Type); err != nil {
+			v.add(field+".type", "%s %q", err, n.Type)
+		}
+		// only the veth strategy applies the settings of the network
+		if n.Type != "veth" {
+			continue
+		}
+		if n.Bridge == "" {
+			v.add(field+".bridge", "bridge is required for veth networks")
+		}
+		if n.VethPrefix == "" {
+			v.add(field+".veth_prefix", "veth prefix is required for veth networks")
+		}
+		if n.Mtu < 0 {
+			v.add(field+".mtu", "mtu %d cannot be negative", n.Mtu)
+		}
+		if n.TxQueueLen < 0 {
+			v.add(field+".txqueuelen", "txqueuelen %d cannot be negative", n.TxQueueLen)
+		}
+		if n.Address != "" {
+			if ip, _, err := net.ParseCIDR(n.Address); err != nil || ip.To4() == nil {
+				v.add(field+".address", "%q is not an IPv4 CIDR", n.Address)
+			}
+		}
+		if n.IPv6Address != "" {
+			if ip, _, err := net.ParseCIDR(n.IPv6Address); err != nil || ip.To4() != nil {
+				v.add(field+".ipv6_address", "%q is not an IPv6 CIDR", n.IPv6Address)
+			}
+		}
+		if n.Gateway != "" {
+			if ip := net.ParseIP(n.Gateway); ip == nil || ip.To4() == nil {
+				v.add(field+".gateway", "%q is not an IPv4 address", n.Gateway)
+			}
+		}
+		if n.IPv6Gateway != "" {
+			if ip := net.ParseIP(n.IPv6Gateway); ip == nil || ip.To4() != nil {
+				v.add(field+".ipv6_gateway", "%q is not an IPv6 address", n.IPv6Gateway)
+			}
+		}
+		if n.MacAddress != "" {
+			if _, err := net.ParseMAC(n.MacAddress); err != nil {
+				v.add(field+".mac_address", "%q is not a MAC address", n.MacAddress)
+			}
+		}
+	}
+}
+
+func (v *validator) validateRoutes(c *Config) {
+	for i, r := range c.Routes {
+		field := fmt.Sprintf("routes[%d]", i)
+		if r.Destination == "" && r.Source == "" && r.Gateway == "" {
+			v.add(field, "one of destination, source or gateway is required")
+			continue
+		}
+
+		// all of the addresses of a route have to be of the same IP family
+		var ips []net.IP
+		if r.Destination != "" {
+			ip, _, err := net.ParseCIDR(r.Destination)
+			if err != nil {
+				v.add(field+".destination", "%q is not a CIDR", r.Destination)
+			} else {
+				ips = append(ips, ip)
+			}
+		}
+		if r.Source != "" {
+			ip := net.ParseIP(r.Source)
+			if ip == nil {
+				v.add(field+".source", "%q is not an IP address", r.Source)
+			} else {
+				ips = append(ips, ip)
+			}
+		}
+		if r.Gateway != "" {
+			ip := net.ParseIP(r.Gateway)
+			if ip == nil {
+				v.add(field+".gateway", "%q is not an IP address", r.Gateway)
+			} else {
+				ips = append(ips, ip)
+			}
+		}
+		for j := 1; j < len(ips); j++ {
+			if (ips[j].To4() == nil) != (ips[0].To4() == nil) {
+				v.add(field, "destination, source and gateway must be of the same IP family")
+				break
+			}
+		}
+	}
+}
+
+func (v *validator) validateHooks(c *Config) {
+	if c.Hooks == nil {
+		return
+	}
+
+	for _, hooks := range []struct {
+		name  string
+		hooks []*Hook
+	}{
+		{"prestart", c.Hooks.Prestart},
+		{"poststart", c.Hooks.Poststart},
+		{"poststop", c.Hooks.Poststop},
+	} {
+		for i, h := range hooks.hooks {
+			field := fmt.Sprintf("hooks.%s[%d]", hooks.name, i)
+			if !filepath.IsAbs(h.Path) {
+				v.add(field+".path", "path %q is not absolute", h.Path)
+			}
+			if h.Timeout < 0 {
+				v.add(field+".timeout", "timeout %d cannot be negative", h.Timeout)
+			}
+		}
+	}
+}
+
+func (v *validator) validateStopSignal(c *Config) {
+	// signals are numbered from 1 to SIGRTMAX which is 64 on linux
+	if c.StopSignal < 0 || c.StopSignal > 64 {
+		v.add("stop_signal", "invalid signal %d", c.StopSignal)
+	}
+}
+
+// ipcSysctls are the sysctls of the IPC namespace besides the fs.mqueue.* ones.
+var ipcSysctls = map[string]bool{
+	"kernel.msgmax":          true,
+	"kernel.msgmnb":          true,
+	"kernel.msgmni":          true,
+	"kernel.sem":             true,
+	"kernel.shmall":          true,
+	"kernel.shmmax":          true,
+	"kernel.shmmni":          true,
+	"kernel.shm_rmid_forced": true,
+}
+
+// validateSysctl only accepts the sysctls of namespaces that are created for the
+// container, any other sysctl would change the host or the namespaces of other
+// processes.
+func (v *validator) validateSysctl(c *Config) {
+	for key := range c.Sysctl {
+		field := fmt.Sprintf("sysctl[%s]", key)
+		if key == "" || strings.Contains(key, "/") || strings.Contains(key, "..") || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+			v.add(field, "invalid sysctl name %q", key)
+			continue
+		}
+
+		var t NamespaceType
+		switch {
+		case strings.HasPrefix(key, "net."):
+			t = NEWNET
+		case ipcSysctls[key], strings.HasPrefix(key, "fs.mqueue."):
+			t = NEWIPC
+		case key == "kernel.domainname":
+			t = NEWUTS
+		case key == "kernel.hostname":
+			v.add(field, "the hostname is set with hostname")
+			continue
+		default:
+			v.add(field, "sysctl %q is not namespaced and would change the host", key)
+			continue
+		}
+		if !createsNamespace(c, t) {
+			v.add(field, "sysctl %q requires a new %s namespace", key, t)
+		}
+	}
+}
+
+// createsNamespace returns true if a new namespace of type t is created for the
+// container rather than joined.
+func createsNamespace(c *Config, t NamespaceType) bool {
+	for _, ns := range c.Namespaces {
+		if ns.Type == t {
+			return ns.Path == "" && ns.Container == ""
+		}
+	}
+	return false
+}
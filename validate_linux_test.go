@@ -0,0 +1,305 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/devices"
+	"github.com/docker/libcontainer/mount"
+)
+
+func TestValidateSampleConfigs(t *testing.T) {
+	files, err := ioutil.ReadDir("sample_configs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.Name() == "README.md" {
+			continue
+		}
+		container, err := loadConfig(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := container.Validate(); err != nil {
+			t.Fatalf("expected %s to be valid but received %s", f.Name(), err)
+		}
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	container := &Config{
+		RootFs:       "/non/existent/rootfs",
+		Hostname:     "koye",
+		Env:          []string{"PATH"},
+		Capabilities: []string{"CHOWN", "NOT_A_CAP"},
+		MountConfig:  &MountConfig{},
+		Namespaces: Namespaces{
+			{Type: NEWPID},
+			{Type: NEWPID},
+			{Type: "NEWFOO"},
+		},
+		Networks: []*Network{
+			{Type: "veth", Address: "10.0.0.2"},
+		},
+		Routes: []*Route{
+			{Destination: "10.0.0.0/8", Gateway: "::1"},
+		},
+		Cgroups: &cgroups.Cgroup{
+			Name:       "test",
+			Memory:     2048,
+			MemorySwap: 1024,
+			CpusetCpus: "3-1",
+			AllowedDevices: []*devices.Device{
+				{Type: 'x', CgroupPermissions: "rwx"},
+			},
+		},
+	}
+
+	err := container.Validate()
+	if err == nil {
+		t.Fatal("expected errors validating the config")
+	}
+	verrs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors but received %T", err)
+	}
+
+	fields := make(map[string]bool)
+	for _, e := range verrs {
+		fields[e.Field] = true
+	}
+	for _, expected := range []string{
+		"root_fs",
+		"hostname",
+		"environment[0]",
+		"capabilities[1]",
+		"mount_config",
+		"namespaces[1].type",
+		"namespaces[2].type",
+		"networks",
+		"networks[0].bridge",
+		"networks[0].veth_prefix",
+		"networks[0].address",
+		"routes",
+		"routes[0]",
+		"cgroups.memory_swap",
+		"cgroups.cpuset_cpus",
+		"cgroups.allowed_devices[0].type",
+		"cgroups.allowed_devices[0].cgroup_permissions",
+	} {
+		if !fields[expected] {
+			t.Errorf("expected an error for field %s in %s", expected, err)
+		}
+	}
+	if contains("capabilities[0]", keys(fields)) {
+		t.Errorf("unexpected error for a valid capability in %s", err)
+	}
+}
+
+func TestValidateRootFsNotDirectory(t *testing.T) {
+	f, err := ioutil.TempFile("", "rootfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	container := &Config{RootFs: f.Name()}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok || len(verrs) != 1 || verrs[0].Field != "root_fs" {
+		t.Fatalf("expected a single root_fs error but received %v", verrs)
+	}
+}
+
+func TestValidateBuiltinInitRequiresPidNamespace(t *testing.T) {
+	container := &Config{BuiltinInit: true}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok || len(verrs) != 1 || verrs[0].Field != "builtin_init" {
+		t.Fatalf("expected a single builtin_init error but received %v", verrs)
+	}
+
+	container.Namespaces = Namespaces{{Type: NEWPID}}
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected builtin_init with the NEWPID namespace to be valid but received %s", err)
+	}
+}
+
+func TestValidateCgroupsWithoutName(t *testing.T) {
+	// without a name the container is placed in the parent cgroup
+	container := &Config{
+		Cgroups: &cgroups.Cgroup{Parent: "integration"},
+	}
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected a cgroup without a name to be valid but received %s", err)
+	}
+}
+
+func TestValidateCgroupMountRequiresCgroupNamespace(t *testing.T) {
+	container := &Config{
+		Namespaces: Namespaces{{Type: NEWNS}},
+		MountConfig: &MountConfig{
+			Mounts: []*mount.Mount{{Type: "cgroup", Destination: "/sys/fs/cgroup"}},
+		},
+	}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok || len(verrs) != 1 || verrs[0].Field != "mount_config.mounts[0].type" {
+		t.Fatalf("expected a single mount_config.mounts[0].type error but received %v", verrs)
+	}
+
+	container.Namespaces = append(container.Namespaces, Namespace{Type: NEWCGROUP})
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected a cgroup mount with the NEWCGROUP namespace to be valid but received %s", err)
+	}
+}
+
+func TestValidateMountPropagation(t *testing.T) {
+	container := &Config{
+		Namespaces: Namespaces{{Type: NEWNS}},
+		MountConfig: &MountConfig{
+			RootfsPropagation: "slaved",
+			Mounts: []*mount.Mount{
+				{Type: "tmpfs", Destination: "/tmp", Propagation: "rshared"},
+				{Type: "tmpfs", Destination: "/run", Propagation: "unbindable"},
+			},
+		},
+	}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok || len(verrs) != 2 {
+		t.Fatalf("expected two validation errors but received %v", container.Validate())
+	}
+	if verrs[0].Field != "mount_config.mounts[1].propagation" || verrs[1].Field != "mount_config.rootfs_propagation" {
+		t.Fatalf("expected the propagation of the second mount and the rootfs to be rejected but received %v", verrs)
+	}
+
+	container.MountConfig.RootfsPropagation = "rprivate"
+	container.MountConfig.Mounts[1].Propagation = "private"
+	verrs, ok = container.Validate().(ValidationErrors)
+	if !ok || len(verrs) != 1 || verrs[0].Field != "mount_config.mounts[0].propagation" {
+		t.Fatalf("expected a shared mount to require a shared or slave rootfs but received %v", container.Validate())
+	}
+
+	container.MountConfig.RootfsPropagation = "rslave"
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected the propagation modes to be valid but received %s", err)
+	}
+}
+
+func TestValidateUserNamespace(t *testing.T) {
+	container := &Config{
+		Namespaces:  Namespaces{{Type: NEWUSER}},
+		UidMappings: []IDMap{{ContainerID: 0, HostID: 100000, Size: 1000}, {ContainerID: 500, HostID: 200000, Size: 1}},
+		GidMappings: []IDMap{{ContainerID: 1, HostID: 100000, Size: 0}},
+	}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors but received %v", container.Validate())
+	}
+	fields := make(map[string]bool)
+	for _, e := range verrs {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"uid_mappings[1]", "gid_mappings[0].size", "gid_mappings"} {
+		if !fields[field] {
+			t.Errorf("expected an error for %s but received %v", field, verrs)
+		}
+	}
+
+	container.UidMappings = container.UidMappings[:1]
+	container.GidMappings = []IDMap{{ContainerID: 0, HostID: 100000, Size: 1000}}
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected the mappings to be valid but received %s", err)
+	}
+	if uid, err := container.HostUID(); err != nil || uid != 100000 {
+		t.Fatalf("expected host uid 100000 but received %d %v", uid, err)
+	}
+
+	container.Namespaces = nil
+	verrs, ok = container.Validate().(ValidationErrors)
+	if !ok || len(verrs) != 2 {
+		t.Fatalf("expected errors for mappings without NEWUSER but received %v", verrs)
+	}
+}
+
+func TestValidateRootless(t *testing.T) {
+	container := &Config{
+		Rootless:    true,
+		Namespaces:  Namespaces{{Type: NEWUSER}, {Type: NEWNS}, {Type: NEWPID}, {Type: NEWNET}},
+		UidMappings: []IDMap{{ContainerID: 0, HostID: 1000, Size: 1}},
+		GidMappings: []IDMap{{ContainerID: 0, HostID: 1000, Size: 2}},
+		Networks:    []*Network{{Type: "loopback"}, {Type: "veth", Bridge: "docker0", VethPrefix: "veth"}},
+	}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors but received %v", container.Validate())
+	}
+	fields := make(map[string]bool)
+	for _, e := range verrs {
+		fields[e.Field] = true
+	}
+	expected := []string{"gid_mappings", "namespaces", "networks[1].type"}
+	if len(verrs) != len(expected) {
+		t.Fatalf("expected errors for %v but received %v", expected, verrs)
+	}
+	for _, field := range expected {
+		if !fields[field] {
+			t.Errorf("expected an error for %s but received %v", field, verrs)
+		}
+	}
+
+	container.GidMappings[0].Size = 1
+	container.Namespaces = append(container.Namespaces, Namespace{Type: NEWIPC})
+	container.Networks = container.Networks[:1]
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected the rootless config to be valid but received %s", err)
+	}
+}
+
+func keys(m map[string]bool) []string {
+	out := []string{}
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestValidateSysctl(t *testing.T) {
+	container := &Config{
+		Namespaces: Namespaces{{Type: NEWNET}, {Type: NEWIPC, Path: "/proc/1/ns/ipc"}},
+		Sysctl: map[string]string{
+			"net.core.somaxconn": "1024",
+			"kernel.shmmax":      "4096",
+			"fs.mqueue.msg_max":  "20",
+			"kernel.domainname":  "example.com",
+			"vm.swappiness":      "0",
+			"net/../../kernel":   "1",
+		},
+	}
+	verrs, ok := container.Validate().(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors but received %v", container.Validate())
+	}
+	fields := make(map[string]bool)
+	for _, e := range verrs {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"sysctl[kernel.shmmax]", "sysctl[fs.mqueue.msg_max]", "sysctl[kernel.domainname]", "sysctl[vm.swappiness]", "sysctl[net/../../kernel]"} {
+		if !fields[field] {
+			t.Errorf("expected an error for %s but received %v", field, verrs)
+		}
+	}
+	if len(verrs) != 5 {
+		t.Fatalf("expected 5 errors but received %v", verrs)
+	}
+
+	container.Namespaces = Namespaces{{Type: NEWNET}, {Type: NEWIPC}, {Type: NEWUTS}}
+	delete(container.Sysctl, "vm.swappiness")
+	delete(container.Sysctl, "net/../../kernel")
+	if err := container.Validate(); err != nil {
+		t.Fatalf("expected the sysctls of new namespaces to be valid but received %s", err)
+	}
+}